@@ -0,0 +1,51 @@
+/*
+Command day13gif draws the day 13 carts going round the tracks as an animated
+GIF, up to the last crash.
+
+	go run ./cmd/day13gif -input day13/input_test2.txt -out carts.gif
+	go run ./cmd/day13gif -skip 20 -crop -collisions bounce -max-ticks 2000
+*/
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/alanbriolat/AdventOfCode2018/day13"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+)
+
+var input = flag.String("input", "day13/input.txt", "simulate the carts on the tracks in `file`")
+var out = flag.String("out", "day13.gif", "write the animation to `file`")
+var defaults = day13.DefaultGIFOptions()
+var skip = flag.Int("skip", defaults.FrameSkip, "only draw every `n`th tick")
+var crop = flag.Bool("crop", defaults.Crop, "only draw the area the carts visit")
+var scale = flag.Int("scale", defaults.Scale, "draw each square as `n`x`n` pixels")
+var delay = flag.Int("delay", defaults.Delay, "time between frames in 100ths of a second")
+var maxTicks = flag.Int("max-ticks", defaults.MaxTicks, "stop after `n` ticks if the carts are still crashing")
+var collisions = flag.String("collisions", "remove", "what happens when carts collide: remove, bounce or pass")
+var turns = flag.String("turns", "LSR", "sequence of turns at intersections: L(eft), S(traight), R(ight), B(ack)")
+var show = flag.Bool("print", false, "also print the tracks at the end")
+
+func main() {
+	flag.Parse()
+
+	lines, err := util.ReadLinesFromFile(*input)
+	util.Check(err)
+	cs := day13.NewCartSystem(lines)
+	cs.Collisions, err = day13.ParseCollisionPolicy(*collisions)
+	util.Check(err)
+	cs.Turns, err = day13.ParseTurns(*turns)
+	util.Check(err)
+
+	opts := day13.GIFOptions{FrameSkip: *skip, Crop: *crop, Scale: *scale, Delay: *delay, MaxTicks: *maxTicks}
+	f, err := os.Create(*out)
+	util.Check(err)
+	util.Check(cs.WriteGIF(f, opts))
+	util.Check(f.Close())
+
+	if *show {
+		cs.RunUntilRemaining(1, *maxTicks)
+		os.Stdout.WriteString(cs.String())
+	}
+}
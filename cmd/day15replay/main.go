@@ -0,0 +1,100 @@
+/*
+Command day15replay steps through a day 15 battle round by round, either by
+simulating it from an input map or by loading a recording saved earlier.
+
+	go run ./cmd/day15replay -input day15/input_test1.txt -save battle.jsonl
+	go run ./cmd/day15replay -load battle.jsonl
+	go run ./cmd/day15replay -load battle.jsonl -play=false -summary -units-csv units.csv
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alanbriolat/AdventOfCode2018/day15"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+)
+
+var input = flag.String("input", "day15/input.txt", "simulate the battle on the map in `file`")
+var rulesFile = flag.String("rules", "", "load combat rules from `file` (default: the puzzle's rules)")
+var power = flag.Int("power", 0, "elf attack `power` (default: from the rules)")
+var load = flag.String("load", "", "play the recording in `file` instead of simulating")
+var save = flag.String("save", "", "save the recording to `file` as JSON Lines")
+var maxRounds = flag.Int("max-rounds", 10000, "give up if combat hasn't ended after `n` rounds, e.g. if no unit can reach an enemy")
+var play = flag.Bool("play", true, "step through the recording interactively")
+var summary = flag.Bool("summary", false, "print a summary of how the battle went")
+var unitsCSV = flag.String("units-csv", "", "write statistics for each unit to `file` as CSV")
+var roundsCSV = flag.String("rounds-csv", "", "write each faction's hit points after each round to `file` as CSV")
+
+func main() {
+	flag.Parse()
+
+	var recording *day15.Recording
+	if *load != "" {
+		f, err := os.Open(*load)
+		util.Check(err)
+		recording, err = day15.ReadRecording(f, *load)
+		f.Close()
+		util.Check(err)
+	} else {
+		lines, err := util.ReadLinesFromFile(*input)
+		util.Check(err)
+		rules := day15.DefaultRules()
+		if *rulesFile != "" {
+			rules, err = day15.LoadRules(*rulesFile)
+			util.Check(err)
+		}
+		util.Check(rules.CheckMap(lines))
+		battle := day15.NewBattleWithRules(lines, rules)
+		if *power > 0 {
+			if _, ok := rules.ElfFaction(); !ok {
+				util.Check(fmt.Errorf("-power needs the rules to name the elves"))
+			}
+			battle.BuffElves(*power)
+		}
+		recording = day15.NewRecording(lines, rules)
+		battle.OnEvent = recording.Record
+		for !battle.NextRound() {
+			if battle.Round >= *maxRounds {
+				fmt.Fprintf(os.Stderr, "combat still going after %d rounds, giving up\n", battle.Round)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if *save != "" {
+		util.Check(writeFile(*save, recording.WriteJSONLines))
+	}
+
+	if *summary || *unitsCSV != "" || *roundsCSV != "" {
+		analytics := day15.AnalyseRecording(recording)
+		if *summary {
+			fmt.Print(analytics.Summary())
+		}
+		if *unitsCSV != "" {
+			util.Check(writeFile(*unitsCSV, analytics.WriteUnitsCSV))
+		}
+		if *roundsCSV != "" {
+			util.Check(writeFile(*roundsCSV, analytics.WriteRoundsCSV))
+		}
+	}
+
+	if *play {
+		util.Check(day15.Play(os.Stdin, os.Stdout, recording))
+	}
+}
+
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -3,31 +3,30 @@ package day01
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 )
 
 type state struct {
 	Frequency   int
-	seen        map[int]bool
+	seen        util.Set[int]
 	FoundRepeat bool
 	Repeat      int
 }
 
 func State() state {
-	return state{0,map[int]bool{0: true},false,0}
+	return state{0,util.NewSet(0),false,0}
 }
 
 func (s *state) Update(change int) {
 	s.Frequency += change
-	if !s.FoundRepeat && s.seen[s.Frequency] {
+	if !s.FoundRepeat && s.seen.Contains(s.Frequency) {
 		s.FoundRepeat = true
 		s.Repeat = s.Frequency
 	}
-	s.seen[s.Frequency] = true
+	s.seen.Add(s.Frequency)
 }
 
 
-func part1and2(logger *log.Logger) string {
+func part1and2(logger *util.Logger) string {
 	changes, err := util.ReadIntsFromFile("day01/input1.txt")
 	util.Check(err)
 	state := State()
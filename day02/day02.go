@@ -3,7 +3,6 @@ package day02
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"strings"
 )
 
@@ -71,7 +70,7 @@ func SharedString(s1, s2 string) string {
 	return builder.String()
 }
 
-func part1and2(logger *log.Logger) string {
+func part1and2(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 	lines, err := util.ReadLinesFromFile("day02/input1.txt")
@@ -1,22 +1,14 @@
 package day03
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"io"
-	"log"
-	"math"
-	"os"
-	"strconv"
 )
 
 type Square struct {
 	X, Y int
 }
 
-type Point Square
-
 type ClaimError string
 
 func (e ClaimError) Error() string {
@@ -75,63 +67,30 @@ func LinearOverlap(p1, d1, p2, d2 int) (p, d int) {
 	return p2, util.MinInt(p1+d1-p2, d2)
 }
 
-func ReadClaimsFromFile(name string) (result []Claim, min, max Point, err error) {
-	result = make([]Claim, 0, 1500)
-	min = Point{math.MaxInt32, math.MaxInt32}
-	max = Point{math.MinInt32, math.MinInt32}
-	var rawReader io.Reader
-	if rawReader, err = os.Open(name); err != nil {
-		return nil, min, max, err
+func ReadClaimsFromFile(name string) (result []Claim, bounds util.Box2D, err error) {
+	bounds = util.EmptyBox[util.Vec2D]()
+	if result, err = util.ReadRecordsFromFile[Claim](name, "#{Id} @ {X},{Y}: {W}x{H}"); err != nil {
+		return nil, bounds, err
 	}
-	reader := bufio.NewReader(rawReader)
-	for {
-		claim := Claim{}
-		// read start of line
-		bytes, err := reader.ReadBytes('#')
-		if len(bytes) == 0 || err == io.EOF {
-			// end of input
-			break
-		} else {
-			util.Check(err)
-		}
-		// read the claim definition
-		bytes, err = reader.ReadBytes('@')
-		claim.Id, err = strconv.Atoi(string(bytes[:len(bytes)-2]))
-		util.Check(err)
-		bytes, err = reader.ReadBytes(',')
-		claim.X, err = strconv.Atoi(string(bytes[1:len(bytes)-1]))
-		util.Check(err)
-		bytes, err = reader.ReadBytes(':')
-		claim.Y, err = strconv.Atoi(string(bytes[:len(bytes)-1]))
-		util.Check(err)
-		bytes, err = reader.ReadBytes('x')
-		claim.W, err = strconv.Atoi(string(bytes[1:len(bytes)-1]))
-		util.Check(err)
-		bytes, err = reader.ReadBytes('\n')
-		claim.H, err = strconv.Atoi(string(bytes[:len(bytes)-1]))
-		util.Check(err)
-
+	for _, claim := range result {
 		// Keep track of the extent of the fabric
-		min.X = util.MinInt(min.X, claim.X)
-		min.Y = util.MinInt(min.Y, claim.Y)
-		max.X = util.MaxInt(max.X, claim.X + claim.W)
-		max.Y = util.MaxInt(max.Y, claim.Y + claim.H)
-
-		result = append(result, claim)
+		bounds.Extend(util.Vec2D{claim.X, claim.Y})
+		bounds.Extend(util.Vec2D{claim.X + claim.W - 1, claim.Y + claim.H - 1})
 	}
-	return result, min, max, nil
+	return result, bounds, nil
 }
 
-func part1and2(logger *log.Logger) string {
+func part1and2(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
-	claims, min, max, err := ReadClaimsFromFile("day03/input1.txt")
+	claims, bounds, err := ReadClaimsFromFile("day03/input1.txt")
 	util.Check(err)
 	t.LogCheckpoint(fmt.Sprint("read ", len(claims), " claims"))
 
 	// Create grid of claim counts per square
-	width, height := max.X-min.X, max.Y-min.Y
+	min, size := bounds.Min, bounds.Size()
+	width, height := size.X, size.Y
 	rawCounts := make([]int, width*height)
 	counts := make([][]int, width)
 	for i := 0; i < width; i++ {
@@ -12,14 +12,9 @@ Part 1
 package day04
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"io"
-	"log"
-	"os"
 	"sort"
-	"strings"
 	"time"
 )
 
@@ -130,20 +125,21 @@ func (r Roster) FindConsistentlySleepyGuard() (guard *Guard, minute int) {
 
 func ReadEventsFromFile(name string) ([]Event, error) {
 	result := make([]Event, 0, 100)
-	var err error
-	var reader io.Reader
-	if reader, err = os.Open(name); err != nil {
+	reader, err := util.OpenLines(name)
+	if err != nil {
 		return nil, err
 	}
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
+	defer reader.Close()
+	for line := range reader.Lines() {
 		event := Event{}
-		line := scanner.Text()
-		timestamp := string(line[1:17])
-		data := string(line[19:])
+		if len(line.Text) < 19 {
+			return nil, line.Errorf("line too short for an event: %q", line.Text)
+		}
+		timestamp := line.Text[1:17]
+		data := line.Text[19:]
 		event.Timestamp, err = time.Parse("2006-01-02 15:04", timestamp)
 		if err != nil {
-			return nil, err
+			return nil, line.Errorf("failed to read timestamp: %v", err)
 		}
 		switch data {
 		case "falls asleep":
@@ -151,19 +147,18 @@ func ReadEventsFromFile(name string) ([]Event, error) {
 		case "wakes up":
 			event.Type = Wake
 		default:
-			buf := strings.NewReader(data)
-			_, err := fmt.Fscanf(buf, "Guard #%d begins shift", &event.GuardId)
+			_, err := fmt.Sscanf(data, "Guard #%d begins shift", &event.GuardId)
 			if err != nil {
-				return nil, fmt.Errorf("failed to read event type: %v", err)
+				return nil, line.Errorf("failed to read event type: %v", err)
 			}
 			event.Type = Begin
 		}
 		result = append(result, event)
 	}
-	return result, nil
+	return result, reader.Err()
 }
 
-func part1and2(logger *log.Logger) string {
+func part1and2(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
 	"io/ioutil"
-	"log"
 	"unicode"
 )
 
@@ -23,7 +22,7 @@ func CanReact(a, b rune) bool {
 }
 
 func React(bytes []byte) []byte {
-	stack := util.NewByteStack(len(bytes))
+	stack := util.NewStack[byte](len(bytes))
 	for _, b := range bytes {
 		top, ok := stack.Peek()
 		if ok && CanReact(rune(top), rune(b)) {
@@ -58,7 +57,7 @@ func ReadInput(name string) []byte {
 	return bytes
 }
 
-func part1(logger *log.Logger) string {
+func part1(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -72,7 +71,7 @@ func part1(logger *log.Logger) string {
 	return fmt.Sprint(len(polymer))
 }
 
-func part2(logger *log.Logger) string {
+func part2(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
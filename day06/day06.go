@@ -34,27 +34,9 @@ package day06
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"io"
-	"log"
-	"math"
-	"os"
 )
 
-type Point struct {
-	X, Y int
-}
-
-func (p Point) ManhattanDistance(o Point) int {
-	return util.AbsInt(p.X-o.X) + util.AbsInt(p.Y-o.Y)
-}
-
-func MinPoint(p1, p2 Point) Point {
-	return Point{util.MinInt(p1.X, p2.X), util.MinInt(p1.Y, p2.Y)}
-}
-
-func MaxPoint(p1, p2 Point) Point {
-	return Point{util.MaxInt(p1.X, p2.X), util.MaxInt(p1.Y, p2.Y)}
-}
+type Point = util.Vec2D
 
 type Location struct {
 	Coordinates Point
@@ -63,22 +45,20 @@ type Location struct {
 }
 
 type Map struct {
-	Min, Max  Point
+	Bounds    util.Box2D
 	Locations []Location
 }
 
 func NewMap() Map {
 	return Map{
-		Point{math.MaxInt32, math.MaxInt32},
-		Point{math.MinInt32, math.MinInt32},
+		util.EmptyBox[Point](),
 		make([]Location, 0),
 	}
 }
 
 func (m *Map) CreateLocation(p Point) {
 	m.Locations = append(m.Locations, Location{p, false, 0})
-	m.Min = MinPoint(m.Min, p)
-	m.Max = MaxPoint(m.Max, p)
+	m.Bounds.Extend(p)
 }
 
 func (m *Map) ClosestLocation(p Point) (result *Location, ok bool) {
@@ -86,7 +66,7 @@ func (m *Map) ClosestLocation(p Point) (result *Location, ok bool) {
 	resultDistance := 0
 	for i := range m.Locations {
 		l := &m.Locations[i]
-		distance := p.ManhattanDistance(l.Coordinates)
+		distance := p.Sub(l.Coordinates).Manhattan()
 		switch {
 		case distance == resultDistance:
 			result = nil
@@ -104,13 +84,13 @@ containing all locations, so that minimum distance is 1. Marks locations closest
 to a perimeter point as infinite.
 */
 func (m *Map) MarkInfinite() {
-	x, y := m.Min.X-1, m.Min.Y-1
-	for ; x <= m.Max.X+1; x++ {
+	x, y := m.Bounds.Min.X-1, m.Bounds.Min.Y-1
+	for ; x <= m.Bounds.Max.X+1; x++ {
 		if l, ok := m.ClosestLocation(Point{x, y}); ok {
 			l.Infinite = true
 		}
 	}
-	for y++; y <= m.Max.Y+1; y++ {
+	for y++; y <= m.Bounds.Max.Y+1; y++ {
 		if l, ok := m.ClosestLocation(Point{x, y}); ok {
 			l.Infinite = true
 		}
@@ -128,8 +108,8 @@ func (m *Map) MarkInfinite() {
 }
 
 func (m *Map) CalculateAreas() {
-	for x := m.Min.X - 1; x <= m.Max.X+1; x++ {
-		for y := m.Min.Y - 1; y <= m.Max.Y+1; y++ {
+	for x := m.Bounds.Min.X - 1; x <= m.Bounds.Max.X+1; x++ {
+		for y := m.Bounds.Min.Y - 1; y <= m.Bounds.Max.Y+1; y++ {
 			if l, ok := m.ClosestLocation(Point{x, y}); ok {
 				l.Area++
 			}
@@ -151,15 +131,15 @@ func (m *Map) FindMostRemoteLocation() (result *Location) {
 func (m *Map) LocationDistanceSum(p Point) int {
 	result := 0
 	for _, l := range m.Locations {
-		result += p.ManhattanDistance(l.Coordinates)
+		result += p.Sub(l.Coordinates).Manhattan()
 	}
 	return result
 }
 
 func (m *Map) CountPointsWithinRange(r int) int {
 	result := 0
-	for x := m.Min.X - 1; x <= m.Max.X+1; x++ {
-		for y := m.Min.Y - 1; y <= m.Max.Y+1; y++ {
+	for x := m.Bounds.Min.X - 1; x <= m.Bounds.Max.X+1; x++ {
+		for y := m.Bounds.Min.Y - 1; y <= m.Bounds.Max.Y+1; y++ {
 			distanceSum := m.LocationDistanceSum(Point{x, y})
 			if distanceSum < r {
 				result++
@@ -170,23 +150,12 @@ func (m *Map) CountPointsWithinRange(r int) int {
 }
 
 func ReadPoints(name string) []Point {
-	reader, err := os.Open(name)
+	result, err := util.ReadRecordsFromFile[Point](name, "{X}, {Y}")
 	util.Check(err)
-	result := make([]Point, 0)
-	for {
-		p := Point{}
-		n, err := fmt.Fscanf(reader, "%d, %d", &p.X, &p.Y)
-		if n == 0 || err == io.EOF {
-			// end of input
-			break
-		}
-		util.Check(err)
-		result = append(result, p)
-	}
 	return result
 }
 
-func part1(logger *log.Logger) string {
+func part1(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -197,7 +166,7 @@ func part1(logger *log.Logger) string {
 	for _, p := range points {
 		worldMap.CreateLocation(p)
 	}
-	t.LogCheckpoint(fmt.Sprintf("populated map %v to %v", worldMap.Min, worldMap.Max))
+	t.LogCheckpoint(fmt.Sprintf("populated map %v to %v", worldMap.Bounds.Min, worldMap.Bounds.Max))
 
 	worldMap.MarkInfinite()
 	t.LogCheckpoint(fmt.Sprintf("marked locations as infinite"))
@@ -211,7 +180,7 @@ func part1(logger *log.Logger) string {
 	return fmt.Sprint(bestLocation.Area)
 }
 
-func part2(logger *log.Logger) string {
+func part2(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -222,7 +191,7 @@ func part2(logger *log.Logger) string {
 	for _, p := range points {
 		worldMap.CreateLocation(p)
 	}
-	t.LogCheckpoint(fmt.Sprintf("populated map %v to %v", worldMap.Min, worldMap.Max))
+	t.LogCheckpoint(fmt.Sprintf("populated map %v to %v", worldMap.Bounds.Min, worldMap.Bounds.Max))
 
 	area := worldMap.CountPointsWithinRange(10000)
 	t.LogCheckpoint(fmt.Sprintf("found %v points with distance sum < 10000", area))
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"os"
 	"sort"
 )
@@ -15,30 +14,30 @@ type Dependency struct {
 }
 
 type DependencyTree struct {
-	controls map[byte]map[byte]bool
-	depends map[byte]map[byte]bool
+	controls map[byte]util.Set[byte]
+	depends map[byte]util.Set[byte]
 }
 
 func NewDependencyTree() DependencyTree {
 	return DependencyTree{
-		make(map[byte]map[byte]bool),
-		make(map[byte]map[byte]bool),
+		make(map[byte]util.Set[byte]),
+		make(map[byte]util.Set[byte]),
 	}
 }
 
-func (t *DependencyTree) Controls(s byte) map[byte]bool {
+func (t *DependencyTree) Controls(s byte) util.Set[byte] {
 	result, ok := t.controls[s]
 	if !ok {
-		result = make(map[byte]bool)
+		result = util.NewSet[byte]()
 		t.controls[s] = result
 	}
 	return result
 }
 
-func (t *DependencyTree) Depends(s byte) map[byte]bool {
+func (t *DependencyTree) Depends(s byte) util.Set[byte] {
 	result, ok := t.depends[s]
 	if !ok {
-		result = make(map[byte]bool)
+		result = util.NewSet[byte]()
 		t.depends[s] = result
 	}
 	return result
@@ -49,8 +48,8 @@ Record that a depends on b
  */
 func (t *DependencyTree) AddDependency(s, d byte) {
 	// mark the relationship between the two
-	t.Depends(s)[d] = true
-	t.Controls(d)[s] = true
+	t.Depends(s).Add(d)
+	t.Controls(d).Add(s)
 	// make sure the dependency exists in depends list too
 	t.Depends(d)
 }
@@ -65,7 +64,7 @@ func (t *DependencyTree) Resolve(d byte) []byte {
 	for s := range c {
 		// remove d from the list steps it "depends on"
 		sDeps := t.Depends(s)
-		delete(sDeps, d)
+		sDeps.Remove(d)
 		// if there are no more dependencies, we're free to resolve s now
 		if len(sDeps) == 0 {
 			result = append(result, s)
@@ -170,7 +169,7 @@ func ReadDependencies(filename string) []Dependency {
 	return result
 }
 
-func part1(logger *log.Logger) string {
+func part1(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -190,7 +189,7 @@ func part1(logger *log.Logger) string {
 	return string(steps)
 }
 
-func part2(logger *log.Logger) string {
+func part2(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
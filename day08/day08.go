@@ -3,7 +3,6 @@ package day08
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 )
 
 type Node struct {
@@ -38,7 +37,7 @@ func readInput(filename string) Input {
 	return Input{result[:], result[:]}
 }
 
-func part1and2(logger *log.Logger) string {
+func part1and2(logger *util.Logger) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -46,12 +45,12 @@ func part1and2(logger *log.Logger) string {
 	t.LogCheckpoint(fmt.Sprintf("read %v numbers", len(input.Data)))
 
 
-	stack := util.NewGenericStack(0)
+	stack := util.NewStack[*Node](0)
 	stack.Push(NewNode(input.Next(), input.Next()))
 	sum := 0
 	var top *Node
 	for stack.Count() > 0 {
-		top = stack.Top().(*Node)
+		top = stack.Top()
 		switch {
 		case top.UnreadChildren > 0:
 			// Still have child nodes to read - read one and it'll get processed on next loop
@@ -76,7 +75,7 @@ func part1and2(logger *log.Logger) string {
 			// If there are no child nodes, and all metadata has been processed, finished with this node
 			stack.Pop()
 			if stack.Count() > 0 {
-				next := stack.Top().(*Node)
+				next := stack.Top()
 				next.ChildValues = append(next.ChildValues, top.Value)
 			}
 		}
@@ -3,7 +3,6 @@ package day09
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"strings"
 )
 
@@ -96,7 +95,7 @@ func (g *GameState) String() string {
 	return b.String()
 }
 
-func part1impl(logger *log.Logger, players, max int) (highScore int) {
+func part1impl(logger *util.Logger, players, max int) (highScore int) {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -120,13 +119,13 @@ func part1impl(logger *log.Logger, players, max int) (highScore int) {
 	return score
 }
 
-func part1(logger *log.Logger) string {
+func part1(logger *util.Logger) string {
 	//highScore := part1impl(logger, 7, 25)
 	highScore := part1impl(logger, 441, 71032)
 	return fmt.Sprint(highScore)
 }
 
-func part2(logger *log.Logger) string {
+func part2(logger *util.Logger) string {
 	highScore := part1impl(logger, 441, 7103200)
 	return fmt.Sprint(highScore)
 }
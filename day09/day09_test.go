@@ -1,13 +1,13 @@
 package day09
 
 import (
+	"github.com/alanbriolat/AdventOfCode2018/util"
 	"io/ioutil"
-	"log"
 	"testing"
 )
 
 func TestDay09(t *testing.T) {
-	logger := log.New(ioutil.Discard, "", 0)
+	logger := util.NewLogger(ioutil.Discard, util.LevelInfo)
 
 	tables := []struct {
 		players, max, score int
@@ -26,8 +26,6 @@ package day10
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
-	"strconv"
 	"strings"
 )
 
@@ -35,53 +33,40 @@ type Star struct {
 	Position, Velocity util.Vec2D
 }
 
-func ParseStar(star *Star, input string) (err error) {
-	if star.Position.X, err = strconv.Atoi(strings.TrimSpace(string(input[10:16]))); err != nil { return err }
-	if star.Position.Y, err = strconv.Atoi(strings.TrimSpace(string(input[18:24]))); err != nil { return err }
-	if star.Velocity.X, err = strconv.Atoi(strings.TrimSpace(string(input[36:38]))); err != nil { return err }
-	if star.Velocity.Y, err = strconv.Atoi(strings.TrimSpace(string(input[40:42]))); err != nil { return err }
-	return nil
-}
+// Format of a line of input
+const StarFormat = "position=<{Position.X}, {Position.Y}> velocity=<{Velocity.X}, {Velocity.Y}>"
 
 type StarField struct {
 	Stars []Star
 	Time int
-	Lookup map[util.Vec2D]struct{}
-	Min, Max util.Vec2D
+	Lookup util.Set[util.Vec2D]
+	Bounds util.Box2D
 }
 
-func NewStarField(lines []string) (result StarField, err error) {
-	result = StarField{}
-	result.Stars = make([]Star, len(lines))
-	for i, line := range lines {
-		if err = ParseStar(&result.Stars[i], line); err != nil {
-			return result, err
-		}
-	}
+func NewStarField(stars []Star) StarField {
+	result := StarField{Stars: stars}
 	// Make sure lookup has been generated
 	result.TimeTravel(0)
-	return result, nil
+	return result
 }
 
 func (sf *StarField) TimeTravel(time int) {
 	sf.Time = time
-	sf.Min = util.MaxVec2D()
-	sf.Max = util.MinVec2D()
-	sf.Lookup = make(map[util.Vec2D]struct{})
+	sf.Bounds = util.EmptyBox[util.Vec2D]()
+	sf.Lookup = util.NewSet[util.Vec2D]()
 	for i := range sf.Stars {
 		s := &sf.Stars[i]
 		p := s.Position.Add(s.Velocity.Scale(time))
-		sf.Lookup[p] = struct{}{}
-		sf.Min.MinInPlace(p)
-		sf.Max.MaxInPlace(p)
+		sf.Lookup.Add(p)
+		sf.Bounds.Extend(p)
 	}
 }
 
 func (sf *StarField) Show(star string, space string) string {
 	b := strings.Builder{}
-	for y := sf.Min.Y; y <= sf.Max.Y; y++ {
-		for x := sf.Min.X; x <= sf.Max.X; x++ {
-			if _, ok := sf.Lookup[util.Vec2D{x, y}]; ok {
+	for y := sf.Bounds.Min.Y; y <= sf.Bounds.Max.Y; y++ {
+		for x := sf.Bounds.Min.X; x <= sf.Bounds.Max.X; x++ {
+			if sf.Lookup.Contains(util.Vec2D{x, y}) {
 				b.WriteString(star)
 			} else {
 				b.WriteString(space)
@@ -93,7 +78,7 @@ func (sf *StarField) Show(star string, space string) string {
 }
 
 func (sf *StarField) Area() int {
-	return (sf.Max.X - sf.Min.X) * (sf.Max.Y - sf.Min.Y)
+	return sf.Bounds.Max.Sub(sf.Bounds.Min).Area()
 }
 
 /*
@@ -155,16 +140,13 @@ func oscillate(resolution int, fitness func(i int)int, compare func(a, b int)int
 	}
 }
 
-func part1impl(logger *log.Logger, filename string) string {
+func part1impl(logger *util.Logger, filename string) string {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
-	lines, err := util.ReadLinesFromFile(filename)
-	util.Check(err)
-	t.Printf("read %v lines", len(lines))
-
-	starField, err := NewStarField(lines)
+	stars, err := util.ReadRecordsFromFile[Star](filename, StarFormat)
 	util.Check(err)
+	starField := NewStarField(stars)
 	t.Printf("read %v stars", len(starField.Stars))
 
 	//time, err := simpleHillClimbing(
@@ -183,11 +165,11 @@ func part1impl(logger *log.Logger, filename string) string {
 	return fmt.Sprint("after ", time, " time steps:\n", starField.Show("#", " "))
 }
 
-func part0(logger *log.Logger) string {
+func part0(logger *util.Logger) string {
 	return part1impl(logger, "day10/input_test.txt")
 }
 
-func part1and2(logger *log.Logger) string {
+func part1and2(logger *util.Logger) string {
 	return part1impl(logger, "day10/input.txt")
 }
 
@@ -3,7 +3,6 @@ package day11
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"math"
 )
 
@@ -17,22 +16,25 @@ const (
 
 type FuelGrid struct {
 	SerialNo int
-	Grid     util.IntGrid
-	Area     util.IntGrid
+	Grid     util.Grid[int]
+	Area     util.Grid[int]
 }
 
 func NewFuelGrid(serialNo int) FuelGrid {
 	fg := FuelGrid{SerialNo: serialNo}
-	fg.Grid = util.NewIntGrid(SIZE, SIZE)
-	fg.Area = util.NewIntGrid(SIZE, SIZE)
-	for x := 0; x < SIZE; x++ {
-		for y, colSum := 0, 0; y < SIZE; y++ {
-			power := fg.CalcCellPower(x + 1, y + 1)
-			fg.Grid[x][y] = power
+	// Use the same 1-based coordinates as the puzzle
+	origin := util.Vec2D{1, 1}
+	fg.Grid = util.NewGridWithOrigin[int](origin, SIZE, SIZE, util.ColumnMajor)
+	fg.Area = util.NewGridWithOrigin[int](origin, SIZE, SIZE, util.ColumnMajor)
+	for x := 1; x <= SIZE; x++ {
+		for y, colSum := 1, 0; y <= SIZE; y++ {
+			p := util.Vec2D{x, y}
+			power := fg.CalcCellPower(x, y)
+			fg.Grid.Set(p, power)
 			colSum += power
-			fg.Area[x][y] = colSum
-			if x > 0 {
-				fg.Area[x][y] += fg.Area[x-1][y]
+			*fg.Area.At(p) = colSum
+			if x > 1 {
+				*fg.Area.At(p) += fg.Area.Get(util.Vec2D{x - 1, y})
 			}
 		}
 	}
@@ -47,24 +49,21 @@ func (fg *FuelGrid) CalcCellPower(x, y int) int {
 }
 
 func (fg *FuelGrid) CellPower(x, y int) int {
-	return fg.Grid[x-1][y-1]
+	return fg.Grid.Get(util.Vec2D{x, y})
 }
 
 func (fg *FuelGrid) GroupPower(x, y, size int) int {
-	// Adjust to grid coordinates
-	x -= 1
-	y -= 1
 	// Calculate bottom-right corner
 	maxX := x+size-1
 	maxY := y+size-1
 	// Find sum at bottom right corner
-	result := fg.Area[maxX][maxY]
+	result := fg.Area.Get(util.Vec2D{maxX, maxY})
 	// Find sum before bottom left
-	if x > 0 { result -= fg.Area[x-1][maxY] }
+	if x > 1 { result -= fg.Area.Get(util.Vec2D{x-1, maxY}) }
 	// Find sum before top right
-	if y > 0 { result -= fg.Area[maxX][y-1] }
+	if y > 1 { result -= fg.Area.Get(util.Vec2D{maxX, y-1}) }
 	// Find sum before top left
-	if x > 0 && y > 0 { result += fg.Area[x-1][y-1] }
+	if x > 1 && y > 1 { result += fg.Area.Get(util.Vec2D{x-1, y-1}) }
 	return result
 }
 
@@ -93,7 +92,7 @@ func (fg *FuelGrid) FindBestGroupAnySize() (bestX, bestY, bestSize, bestPower in
 	return x, y, size, bestPower
 }
 
-func part1impl(logger *log.Logger, serialNo int) (x, y int) {
+func part1impl(logger *util.Logger, serialNo int) (x, y int) {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -105,7 +104,7 @@ func part1impl(logger *log.Logger, serialNo int) (x, y int) {
 	return x, y
 }
 
-func part2impl(logger *log.Logger, serialNo int) (x, y, size int) {
+func part2impl(logger *util.Logger, serialNo int) (x, y, size int) {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -116,12 +115,12 @@ func part2impl(logger *log.Logger, serialNo int) (x, y, size int) {
 	return x, y, size
 }
 
-func part1(logger *log.Logger) string {
+func part1(logger *util.Logger) string {
 	x, y := part1impl(logger, 7315)
 	return fmt.Sprint(x, ",", y)
 }
 
-func part2(logger *log.Logger) string {
+func part2(logger *util.Logger) string {
 	x, y, size := part2impl(logger, 7315)
 	return fmt.Sprint(x, ",", y, ",", size)
 }
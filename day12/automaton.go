@@ -0,0 +1,296 @@
+package day12
+
+import (
+	"fmt"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"math/bits"
+	"strings"
+)
+
+const (
+	True  = '#'
+	False = '.'
+)
+
+/*
+Rule decides whether a cell is alive in the next generation from its
+neighbourhood, the Radius cells either side of it and the cell itself. The
+neighbourhood is read as a binary number, leftmost cell first, as in
+patternIndex.
+*/
+type Rule struct {
+	Radius int
+	next   []bool
+}
+
+func NewRule(radius int) Rule {
+	return Rule{Radius: radius, next: make([]bool, 1<<(2*radius+1))}
+}
+
+// Number of cells in a neighbourhood
+func (r Rule) Size() int {
+	return 2*r.Radius + 1
+}
+
+func (r Rule) Set(pattern int, alive bool) {
+	r.next[pattern] = alive
+}
+
+func (r Rule) Apply(pattern int) bool {
+	return r.next[pattern]
+}
+
+/*
+WolframRule creates a rule from its number in Wolfram's scheme, where bit i of
+number is the next state for neighbourhood i, e.g. rule 110 with radius 1.
+Only radius 1 and 2 fit in a 64-bit number.
+*/
+func WolframRule(number uint64, radius int) (Rule, error) {
+	if radius < 1 || radius > 2 {
+		return Rule{}, fmt.Errorf("radius %d doesn't fit in a rule number", radius)
+	}
+	r := NewRule(radius)
+	if number>>len(r.next) != 0 {
+		return Rule{}, fmt.Errorf("rule %d is too big for radius %d", number, radius)
+	}
+	for i := range r.next {
+		r.next[i] = number&(1<<i) != 0
+	}
+	return r, r.check()
+}
+
+// Number of the rule in Wolfram's scheme, if it fits in 64 bits
+func (r Rule) Number() (uint64, bool) {
+	if len(r.next) > 64 {
+		return 0, false
+	}
+	number := uint64(0)
+	for i, alive := range r.next {
+		if alive {
+			number |= 1 << i
+		}
+	}
+	return number, true
+}
+
+/*
+ParsePotRules creates a rule from lines like "..#.# => #", with the radius
+from the length of the patterns. Patterns not listed stay dead, because the
+example input only lists the ones that come alive.
+*/
+func ParsePotRules(lines []string) (Rule, error) {
+	if len(lines) == 0 {
+		return Rule{}, fmt.Errorf("no rules")
+	}
+	size := strings.Index(lines[0], " ")
+	if size < 1 || size%2 == 0 {
+		return Rule{}, fmt.Errorf("pattern in %q should be an odd number of pots", lines[0])
+	}
+	r := NewRule(size / 2)
+	for _, line := range lines {
+		pattern, result, ok := strings.Cut(line, " => ")
+		if !ok || len(pattern) != size || len(result) != 1 {
+			return Rule{}, fmt.Errorf("invalid rule %q", line)
+		}
+		r.Set(patternIndex(pattern), result[0] == True)
+	}
+	return r, r.check()
+}
+
+// Only rules that leave empty space empty can be simulated, otherwise infinitely many cells are alive
+func (r Rule) check() error {
+	if r.next[0] {
+		return fmt.Errorf("rule brings empty space to life")
+	}
+	return nil
+}
+
+/*
+patternIndex treats a pattern of cells as a binary string, leftmost cell
+first, turning it into an integer.
+ */
+func patternIndex(pattern string) int {
+	index := 0
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != False {
+			index |= 1 << uint8(len(pattern)-i-1)
+		}
+	}
+	return index
+}
+
+/*
+CellularAutomaton is a one-dimensional cellular automaton on an infinite line
+of cells. Only the span from the first to the last live cell is stored, one bit
+per cell, along with where the span starts. A new span is allocated every
+generation, so copies of the automaton can be kept as a history.
+*/
+type CellularAutomaton struct {
+	Rule       Rule
+	Generation int
+	// Position of the first live cell
+	Origin int
+	// Number of cells from the first live cell to the last, inclusive
+	Length int
+	cells  []uint64
+}
+
+// Create an automaton with the live cells (True) of state, starting at position origin
+func NewCellularAutomaton(rule Rule, state string, origin int) CellularAutomaton {
+	ca := CellularAutomaton{Rule: rule}
+	b := spanBuilder{cells: make([]uint64, (len(state)+63)/64), first: -1}
+	for i := 0; i < len(state); i++ {
+		b.add(i, state[i] == True)
+	}
+	ca.Origin, ca.Length, ca.cells = b.finish(origin)
+	return ca
+}
+
+// Collects live cells into a new span, skipping dead cells at each end
+type spanBuilder struct {
+	cells       []uint64
+	first, last int
+}
+
+func (b *spanBuilder) add(i int, alive bool) {
+	if !alive {
+		return
+	}
+	if b.first < 0 {
+		b.first = i
+	}
+	j := i - b.first
+	b.cells[j/64] |= 1 << (j % 64)
+	b.last = i
+}
+
+// Origin, length and cells of the span, where position origin was added as 0
+func (b *spanBuilder) finish(origin int) (int, int, []uint64) {
+	if b.first < 0 {
+		return 0, 0, nil
+	}
+	length := b.last - b.first + 1
+	return origin + b.first, length, b.cells[:(length+63)/64]
+}
+
+// Is cell i of the span alive?
+func (ca *CellularAutomaton) bit(i int) bool {
+	return i >= 0 && i < ca.Length && ca.cells[i/64]&(1<<(i%64)) != 0
+}
+
+// Is the cell at position x alive?
+func (ca *CellularAutomaton) Alive(x int) bool {
+	return ca.bit(x - ca.Origin)
+}
+
+func (ca *CellularAutomaton) Advance() {
+	r := ca.Rule.Radius
+	mask := 1<<ca.Rule.Size() - 1
+	// The span can grow by at most the radius at each end
+	n := ca.Length + 2*r
+	b := spanBuilder{cells: make([]uint64, (n+63)/64), first: -1}
+	// Slide the neighbourhood along, one cell at a time: new cell i is
+	// centred on span cell i-r, so its neighbourhood ends at span cell i
+	pattern := 0
+	for i := 0; i < n; i++ {
+		pattern <<= 1
+		if ca.bit(i) {
+			pattern |= 1
+		}
+		pattern &= mask
+		b.add(i, ca.Rule.Apply(pattern))
+	}
+	ca.Origin, ca.Length, ca.cells = b.finish(ca.Origin - r)
+	ca.Generation++
+}
+
+/*
+Pattern gets the live cells of the current state as a string, from the first
+live cell to the last, along with the position of the first live cell. The
+next state only depends on the pattern, so two states with the same pattern
+are the same state, just translated.
+ */
+func (ca *CellularAutomaton) Pattern() (string, int) {
+	b := strings.Builder{}
+	b.Grow(ca.Length)
+	for i := 0; i < ca.Length; i++ {
+		if ca.bit(i) {
+			b.WriteByte(True)
+		} else {
+			b.WriteByte(False)
+		}
+	}
+	return b.String(), ca.Origin
+}
+
+func (ca *CellularAutomaton) String() string {
+	pattern, origin := ca.Pattern()
+	return fmt.Sprintf("%02d: %s (from %d)", ca.Generation, pattern, origin)
+}
+
+func (ca *CellularAutomaton) LiveCount() int {
+	count := 0
+	for _, c := range ca.cells {
+		count += bits.OnesCount64(c)
+	}
+	return count
+}
+
+// Sum of the positions of the live cells
+func (ca *CellularAutomaton) IndexSum() int {
+	sum := 0
+	for w, c := range ca.cells {
+		for c != 0 {
+			i := bits.TrailingZeros64(c)
+			sum += ca.Origin + w*64 + i
+			c &= c - 1
+		}
+	}
+	return sum
+}
+
+/*
+Translation describes an automaton whose live pattern repeats exactly, moved
+along by Shift cells, every Cycle.Length generations from Cycle.Start onwards
+(counting from the generation it was found from). A glider has Length 1, but
+the pattern can change shape in between repeats, or stay where it is.
+*/
+type Translation struct {
+	Cycle util.Cycle
+	Shift int
+	// Every generation up to the first repeat
+	history []CellularAutomaton
+}
+
+/*
+FindTranslation advances ca until its live pattern is the same as in an
+earlier generation, giving up after limit generations.
+*/
+func FindTranslation(ca CellularAutomaton, limit int) (*Translation, bool) {
+	next := func(ca CellularAutomaton) CellularAutomaton {
+		ca.Advance()
+		return ca
+	}
+	key := func(ca CellularAutomaton) string {
+		pattern, _ := ca.Pattern()
+		return pattern
+	}
+	cycle, history, ok := util.HistoryCycle(ca, next, key, limit)
+	if !ok {
+		return nil, false
+	}
+	shift := history[cycle.Start+cycle.Length].Origin - history[cycle.Start].Origin
+	return &Translation{cycle, shift, history}, true
+}
+
+/*
+At gets the automaton at generation n, which must not be before the one it
+was found from, by moving the same pattern from within the first cycle.
+*/
+func (t *Translation) At(n int) CellularAutomaton {
+	steps := n - t.history[0].Generation
+	ca := t.history[t.Cycle.Equivalent(steps)]
+	ca.Origin += t.Cycle.Repeats(steps) * t.Shift
+	ca.Generation = n
+	return ca
+}
@@ -1,6 +1,8 @@
 package day12
 
 import (
+	"fmt"
+	"github.com/alanbriolat/AdventOfCode2018/util"
 	"testing"
 )
 
@@ -25,3 +27,116 @@ func TestPatternIndex(t *testing.T) {
 		}
 	}
 }
+
+func TestWolframRules(t *testing.T) {
+	tables := []struct {
+		rule     uint64
+		patterns []string
+		origins  []int
+	}{
+		{90, []string{"#", "#.#", "#...#", "#.#.#.#"}, []int{0, -1, -2, -3}},
+		{110, []string{"#", "##", "###", "##.#"}, []int{0, -1, -2, -3}},
+		// Shifts left one cell every generation
+		{2, []string{"#", "#", "#"}, []int{0, -1, -2}},
+	}
+
+	for _, table := range tables {
+		rule, err := WolframRule(table.rule, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ca := NewCellularAutomaton(rule, "#", 0)
+		for i := range table.patterns {
+			pattern, origin := ca.Pattern()
+			if pattern != table.patterns[i] || origin != table.origins[i] {
+				t.Errorf("rule %d: expected %s from %d, got %v", table.rule, table.patterns[i], table.origins[i], ca.String())
+			}
+			ca.Advance()
+		}
+	}
+
+	if _, err := WolframRule(1, 1); err == nil {
+		t.Errorf("expected error for rule bringing empty space to life")
+	}
+	if _, err := WolframRule(256, 1); err == nil {
+		t.Errorf("expected error for rule number too big")
+	}
+}
+
+func TestPotRules(t *testing.T) {
+	ca := readInput("input_test.txt")
+	for ca.Generation < 20 {
+		ca.Advance()
+	}
+	if pattern, origin := ca.Pattern(); pattern != "#....##....#####...#######....#.#..##" || origin != -2 {
+		t.Errorf("unexpected state %v", ca.String())
+	}
+	if ca.IndexSum() != 325 || ca.LiveCount() != 19 {
+		t.Errorf("expected sum 325 of 19 pots, got %d of %d", ca.IndexSum(), ca.LiveCount())
+	}
+
+	// Same rule from its number
+	number, _ := ca.Rule.Number()
+	rule, err := WolframRule(number, 2)
+	if err != nil || fmt.Sprint(rule) != fmt.Sprint(ca.Rule) {
+		t.Errorf("expected %v, got %v, %v", ca.Rule, rule, err)
+	}
+	if _, err := ParsePotRules([]string{"#### => #"}); err == nil {
+		t.Errorf("expected error for even sized pattern")
+	}
+}
+
+func TestTranslation(t *testing.T) {
+	tables := []struct {
+		rule    uint64
+		initial string
+		length  int
+		shift   int
+	}{
+		// Glider moving left
+		{2, "#", 1, -1},
+		// Alternates between # and ##, moving left 2 every other generation
+		{6, "#", 2, -2},
+		// Sierpinski triangle never repeats
+		{90, "#", 0, 0},
+	}
+
+	for _, table := range tables {
+		rule, err := WolframRule(table.rule, 1)
+		util.Check(err)
+		ca := NewCellularAutomaton(rule, table.initial, 0)
+		translation, ok := FindTranslation(ca, 100)
+		if table.length == 0 {
+			if ok {
+				t.Errorf("rule %d: unexpected repeat %+v", table.rule, translation.Cycle)
+			}
+			continue
+		}
+		if !ok || translation.Cycle.Length != table.length || translation.Shift != table.shift {
+			t.Fatalf("rule %d: expected period %d shifting %d, got %+v", table.rule, table.length, table.shift, translation)
+		}
+		// Extrapolating matches simulating, whether or not it's a whole number of cycles
+		for ca.Generation < 57 {
+			ca.Advance()
+		}
+		expected := ca.String()
+		actual := translation.At(57)
+		if actual.String() != expected || actual.IndexSum() != ca.IndexSum() {
+			t.Errorf("rule %d: expected %s, got %s", table.rule, expected, actual.String())
+		}
+	}
+
+	// The puzzle example eventually becomes a glider
+	ca := readInput("input_test.txt")
+	translation, ok := FindTranslation(ca, 1000)
+	if !ok {
+		t.Fatal("expected example to repeat")
+	}
+	for ca.Generation < 500 {
+		ca.Advance()
+	}
+	final := translation.At(500)
+	if final.IndexSum() != ca.IndexSum() {
+		t.Errorf("expected %d, got %d", ca.IndexSum(), final.IndexSum())
+	}
+}
@@ -3,8 +3,9 @@ package day13
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
+	"math"
 	"sort"
+	"strings"
 )
 
 const (
@@ -17,14 +18,79 @@ const (
 	CartD     = 'v'
 	CartL     = '<'
 	CartR     = '>'
-	TurnLeft  = 0
-	TurnNone  = 1
-	TurnRight = 2
 )
 
+// What a cart does at an intersection
+type Turn int
+
+const (
+	TurnLeft Turn = iota
+	TurnNone
+	TurnRight
+	// Go back the way it came
+	TurnBack
+)
+
+var turnLetters = "LSRB"
+
+func (t Turn) String() string {
+	return turnLetters[t : t+1]
+}
+
+// The puzzle's turns: left, then straight on, then right, then repeat
+var DefaultTurns = []Turn{TurnLeft, TurnNone, TurnRight}
+
+// Parse a sequence of turns like "LSR", with B meaning turn back
+func ParseTurns(s string) ([]Turn, error) {
+	result := make([]Turn, 0, len(s))
+	for _, c := range s {
+		i := strings.IndexRune(turnLetters, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid turn %q in %q", c, s)
+		}
+		result = append(result, Turn(i))
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no turns")
+	}
+	return result, nil
+}
+
+// What happens when a cart moves onto the same square as another cart
+type CollisionPolicy int
+
+const (
+	// Both carts are removed (the puzzle's rule)
+	CollisionRemove CollisionPolicy = iota
+	// The moving cart goes back to where it was, and both carts reverse
+	CollisionBounce
+	// Nothing happens, the carts just pass through each other
+	CollisionPass
+)
+
+var collisionPolicyNames = []string{"remove", "bounce", "pass"}
+
+func (p CollisionPolicy) String() string {
+	return collisionPolicyNames[p]
+}
+
+func ParseCollisionPolicy(s string) (CollisionPolicy, error) {
+	for i, name := range collisionPolicyNames {
+		if s == name {
+			return CollisionPolicy(i), nil
+		}
+	}
+	return CollisionRemove, fmt.Errorf("unknown collision policy %q", s)
+}
+
 type Cart struct {
-	Position        util.Vec2D
-	Velocity        util.Vec2D
+	// Order of the cart in the input, which never changes
+	Id       int
+	Position util.Vec2D
+	Velocity util.Vec2D
+	// Where the cart came from, to turn back the way it came
+	Previous util.Vec2D
+	// Index into the turn sequence of the next turn to make
 	IntersectAction int
 	Crashed         bool
 }
@@ -32,7 +98,7 @@ type Cart struct {
 func NewCart(x, y int, direction byte) (cart Cart, track byte) {
 	cart = Cart{
 		Position:        util.Vec2D{x, y},
-		IntersectAction: TurnLeft,
+		IntersectAction: 0,
 		Crashed:         false,
 	}
 	switch direction {
@@ -47,39 +113,34 @@ func NewCart(x, y int, direction byte) (cart Cart, track byte) {
 	default:
 		panic(fmt.Sprint("invalid direction: ", direction))
 	}
+	cart.Previous = cart.Position.Sub(cart.Velocity)
 	return
 }
 
 func (c *Cart) RotateCW() {
-	result := util.Vec2D{
-		c.Velocity.Y,
-		c.Velocity.X,
-	}
-	if result.X != 0 {
-		result.X = -result.X
-	}
-	c.Velocity = result
+	c.Velocity = c.Velocity.RotateCW()
 }
 
 func (c *Cart) RotateCCW() {
-	result := util.Vec2D{
-		c.Velocity.Y,
-		c.Velocity.X,
-	}
-	if result.Y != 0 {
-		result.Y = -result.Y
-	}
-	c.Velocity = result
+	c.Velocity = c.Velocity.RotateCCW()
 }
 
-func (c *Cart) HandleIntersection() {
-	switch c.IntersectAction {
+// Turn around, to go back the way the cart came
+func (c *Cart) Reverse() {
+	c.Velocity, c.Previous = c.Previous.Sub(c.Position), c.Position.Add(c.Velocity)
+}
+
+// Make the next of turns, a repeating sequence
+func (c *Cart) HandleIntersection(turns []Turn) {
+	switch turns[c.IntersectAction%len(turns)] {
 	case TurnLeft:
 		c.RotateCCW()
 	case TurnRight:
 		c.RotateCW()
+	case TurnBack:
+		c.Reverse()
 	}
-	c.IntersectAction = (c.IntersectAction + 1) % 3
+	c.IntersectAction = (c.IntersectAction + 1) % len(turns)
 }
 
 func (c *Cart) HandleCorner(corner byte) {
@@ -103,45 +164,71 @@ func (c *Cart) HandleCorner(corner byte) {
 	}
 }
 
+// A cart moving onto the same square as another cart
+type Crash struct {
+	Tick     int
+	Position util.Vec2D
+	// Cart.Id of the cart that moved, then the cart it hit
+	Carts  [2]int
+	Policy CollisionPolicy
+}
+
+func (c Crash) String() string {
+	return fmt.Sprintf("tick %d: cart %d hit cart %d at %d,%d (%v)", c.Tick, c.Carts[0], c.Carts[1], c.Position.X, c.Position.Y, c.Policy)
+}
+
 type CartSystem struct {
 	Width, Height int
-	Carts         []Cart
-	Crashes       []util.Vec2D
-	Tracks        [][]byte
-	Time          int
+	// Carts that haven't been removed, in the order they last moved
+	Carts []Cart
+	// Every collision so far, in the order they happened
+	Crashes []Crash
+	Tracks  util.Grid[byte]
+	Time    int
+	// Sequence of turns each cart makes at intersections, repeated
+	Turns      []Turn
+	Collisions CollisionPolicy
+	// Position of each cart, by Cart.Id, at the start and after each tick
+	trajectories [][]util.Vec2D
 }
 
 func NewCartSystem(input []string) CartSystem {
 	cs := CartSystem{}
-	cs.Height = len(input)
-	cs.Width = len(input[0])
 	cs.Carts = make([]Cart, 0)
-	cs.Crashes = make([]util.Vec2D, 0)
-	cs.Tracks = util.NewByteGrid(cs.Width, cs.Height)
-	for x := 0; x < cs.Width; x++ {
-		for y := 0; y < cs.Height; y++ {
-			track := input[y][x]
-			// If this is a cart, record it and replace it with the correct track
-			switch track {
-			case CartU, CartD, CartL, CartR:
-				var cart Cart
-				cart, track = NewCart(x, y, track)
-				cs.Carts = append(cs.Carts, cart)
-			}
-			cs.Tracks[x][y] = track
+	cs.Crashes = make([]Crash, 0)
+	cs.Tracks = util.ParseGrid(input, func(p util.Vec2D, track byte) byte {
+		// If this is a cart, record it and replace it with the correct track
+		switch track {
+		case CartU, CartD, CartL, CartR:
+			var cart Cart
+			cart, track = NewCart(p.X, p.Y, track)
+			cart.Id = len(cs.Carts)
+			cs.Carts = append(cs.Carts, cart)
+			cs.trajectories = append(cs.trajectories, []util.Vec2D{cart.Position})
 		}
-	}
+		return track
+	})
+	// As wide as the longest line, not the first
+	cs.Width, cs.Height = cs.Tracks.Width(), cs.Tracks.Height()
 	cs.Time = 0
+	cs.Turns = DefaultTurns
+	cs.Collisions = CollisionRemove
 	return cs
 }
 
+/*
+Trajectory gets the position of cart id at the start and after each tick, up
+to the current tick or the tick when it was removed.
+*/
+func (cs *CartSystem) Trajectory(id int) []util.Vec2D {
+	return cs.trajectories[id]
+}
+
 func (cs *CartSystem) Tick() {
 	cs.Time++
 	// Sort carts by position, to process them in the correct order
 	sort.Slice(cs.Carts, func(i, j int) bool {
-		a := &cs.Carts[i]
-		b := &cs.Carts[j]
-		return a.Position.Y < b.Position.Y || (a.Position.Y == b.Position.Y && a.Position.X < b.Position.X)
+		return cs.Carts[i].Position.ReadingLess(cs.Carts[j].Position)
 	})
 
 	// Process each cart: move, turn, collide
@@ -153,18 +240,20 @@ func (cs *CartSystem) Tick() {
 		}
 
 		// Move the cart
+		before := *cart
+		cart.Previous = cart.Position
 		cart.Position.AddInPlace(cart.Velocity)
 		// Update cart state
-		track := cs.Tracks[cart.Position.X][cart.Position.Y]
+		track := cs.Tracks.Get(cart.Position)
 		switch track {
 		case Intersect:
-			cart.HandleIntersection()
+			cart.HandleIntersection(cs.Turns)
 		case CornerL, CornerR:
 			cart.HandleCorner(track)
 		case TrackH, TrackV:
 			// No other state change required
 		default:
-			panic("cart off the track!")
+			panic(fmt.Sprintf("cart %d off the track at %v!", cart.Id, cart.Position))
 		}
 
 		// Check for collision
@@ -178,15 +267,29 @@ func (cs *CartSystem) Tick() {
 				continue
 			}
 			if cart.Position == other.Position {
-				cs.Crashes = append(cs.Crashes, cart.Position)
-				cart.Crashed = true
-				other.Crashed = true
+				cs.Crashes = append(cs.Crashes, Crash{cs.Time, cart.Position, [2]int{cart.Id, other.Id}, cs.Collisions})
+				if cs.Collisions == CollisionPass {
+					// Could be several carts on the same square
+					continue
+				}
+				if cs.Collisions == CollisionBounce {
+					*cart = before
+					cart.Reverse()
+					other.Reverse()
+				} else {
+					cart.Crashed = true
+					other.Crashed = true
+				}
 				// can't collide more than once, because only one cart moved
 				break
 			}
 		}
 	}
 
+	for _, cart := range cs.Carts {
+		cs.trajectories[cart.Id] = append(cs.trajectories[cart.Id], cart.Position)
+	}
+
 	// Clean up crashed carts
 	clean := cs.Carts[:0]
 	for _, cart := range cs.Carts {
@@ -197,7 +300,23 @@ func (cs *CartSystem) Tick() {
 	cs.Carts = clean
 }
 
-func part1(logger *log.Logger, filename string) util.Vec2D {
+// Tick until there have been n crashes, giving up after maxTicks more ticks
+func (cs *CartSystem) RunUntilCrashes(n int, maxTicks int) bool {
+	for end := cs.Time + maxTicks; len(cs.Crashes) < n && cs.Time < end; {
+		cs.Tick()
+	}
+	return len(cs.Crashes) >= n
+}
+
+// Tick until there are at most n carts left, giving up after maxTicks more ticks
+func (cs *CartSystem) RunUntilRemaining(n int, maxTicks int) bool {
+	for end := cs.Time + maxTicks; len(cs.Carts) > n && cs.Time < end; {
+		cs.Tick()
+	}
+	return len(cs.Carts) <= n
+}
+
+func part1(logger *util.Logger, filename string) util.Vec2D {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -205,16 +324,21 @@ func part1(logger *log.Logger, filename string) util.Vec2D {
 	util.Check(err)
 	cs := NewCartSystem(lines)
 	t.Printf("read %vx%v cart system with %v carts", cs.Width, cs.Height, len(cs.Carts))
-
-	for len(cs.Crashes) < 1 {
-		cs.Tick()
+	for _, e := range cs.Validate() {
+		logger.Printf("invalid track: %v", e)
 	}
+
+	cs.RunUntilCrashes(1, math.MaxInt32)
 	logger.Printf("%d crash(es) at tick %d: %v\n", len(cs.Crashes), cs.Time, cs.Crashes)
+	if logger.Enabled(util.LevelDebug) {
+		around := util.BoxAround(cs.Crashes[0].Position).Grow(util.Vec2D{5, 5})
+		logger.Debugf("around the first crash:\n%s", cs.RenderBox(cs.clip(around)))
+	}
 
-	return cs.Crashes[0]
+	return cs.Crashes[0].Position
 }
 
-func part2(logger *log.Logger, filename string) util.Vec2D {
+func part2(logger *util.Logger, filename string) util.Vec2D {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -222,9 +346,13 @@ func part2(logger *log.Logger, filename string) util.Vec2D {
 	util.Check(err)
 	cs := NewCartSystem(lines)
 	t.Printf("read %vx%v cart system with %v carts", cs.Width, cs.Height, len(cs.Carts))
+	for _, e := range cs.Validate() {
+		logger.Printf("invalid track: %v", e)
+	}
 
-	for len(cs.Carts) > 1 {
-		cs.Tick()
+	cs.RunUntilRemaining(1, math.MaxInt32)
+	for _, crash := range cs.Crashes {
+		logger.Debugf("%v", crash)
 	}
 	logger.Printf("%d cart(s) remaining at tick %d: %+v\n", len(cs.Carts), cs.Time, cs.Carts[0])
 
@@ -232,22 +360,22 @@ func part2(logger *log.Logger, filename string) util.Vec2D {
 }
 
 func init() {
-	//util.RegisterSolution("day13part1example", func(logger *log.Logger) string {
+	//util.RegisterSolution("day13part1example", func(logger *util.Logger) string {
 	//	p := part1(logger, "day13/input_test1.txt")
 	//	return fmt.Sprint(p.X, ",", p.Y)
 	//})
 	
-	util.RegisterSolution("day13part1", func(logger *log.Logger) string {
+	util.RegisterSolution("day13part1", func(logger *util.Logger) string {
 		p := part1(logger, "day13/input.txt")
 		return fmt.Sprint(p.X, ",", p.Y)
 	})
 
-	//util.RegisterSolution("day13part2example", func(logger *log.Logger) string {
+	//util.RegisterSolution("day13part2example", func(logger *util.Logger) string {
 	//	p := part2(logger, "day13/input_test2.txt")
 	//	return fmt.Sprint(p.X, ",", p.Y)
 	//})
 
-	util.RegisterSolution("day13part2", func(logger *log.Logger) string {
+	util.RegisterSolution("day13part2", func(logger *util.Logger) string {
 		p := part2(logger, "day13/input.txt")
 		return fmt.Sprint(p.X, ",", p.Y)
 	})
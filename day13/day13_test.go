@@ -0,0 +1,200 @@
+package day13
+
+import (
+	"bytes"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"image/gif"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExamples(t *testing.T) {
+	lines, err := util.ReadLinesFromFile("input_test1.txt")
+	util.Check(err)
+	cs := NewCartSystem(lines)
+	cs.RunUntilCrashes(1, math.MaxInt32)
+	expected := Crash{14, util.Vec2D{7, 3}, [2]int{0, 1}, CollisionRemove}
+	if cs.Crashes[0] != expected {
+		t.Errorf("expected %v, got %v", expected, cs.Crashes[0])
+	}
+	if len(cs.Trajectory(0)) != 15 || cs.Trajectory(0)[14] != (util.Vec2D{7, 3}) {
+		t.Errorf("unexpected trajectory %v", cs.Trajectory(0))
+	}
+
+	lines, err = util.ReadLinesFromFile("input_test2.txt")
+	util.Check(err)
+	cs = NewCartSystem(lines)
+	if !cs.RunUntilRemaining(3, 10) || len(cs.Crashes) != 3 || cs.Time != 1 {
+		t.Errorf("expected 3 crashes in the first tick, got %v", cs.Crashes)
+	}
+	cs.RunUntilRemaining(1, math.MaxInt32)
+	if cs.Carts[0].Position != (util.Vec2D{6, 4}) {
+		t.Errorf("expected last cart at 6,4, got %v", cs.Carts[0].Position)
+	}
+}
+
+func TestCollisionPolicies(t *testing.T) {
+	lines, err := util.ReadLinesFromFile("input_test2.txt")
+	util.Check(err)
+	for _, policy := range []CollisionPolicy{CollisionBounce, CollisionPass} {
+		cs := NewCartSystem(lines)
+		cs.Collisions = policy
+		if cs.RunUntilRemaining(8, 100) || len(cs.Carts) != 9 || len(cs.Crashes) == 0 {
+			t.Errorf("%v: expected all carts to keep going after %d crashes", policy, len(cs.Crashes))
+		}
+		// Carts only ever move along the tracks, one square at a time
+		for _, cart := range cs.Carts {
+			trajectory := cs.Trajectory(cart.Id)
+			for i := 1; i < len(trajectory); i++ {
+				if d := trajectory[i].Sub(trajectory[i-1]).Manhattan(); d > 1 || cs.Tracks.Get(trajectory[i]) == ' ' {
+					t.Fatalf("%v: cart %d left the track at tick %d: %v", policy, cart.Id, i, trajectory)
+				}
+			}
+		}
+	}
+}
+
+func TestTurns(t *testing.T) {
+	turns, err := ParseTurns("LSRB")
+	if err != nil || len(turns) != 4 || turns[3] != TurnBack {
+		t.Errorf("unexpected turns %v, %v", turns, err)
+	}
+	if _, err := ParseTurns("LX"); err == nil {
+		t.Errorf("expected error for invalid turn")
+	}
+
+	// Going straight on, the carts on the outside loop just chase each other
+	lines, err := util.ReadLinesFromFile("input_test1.txt")
+	util.Check(err)
+	cs := NewCartSystem(lines)
+	cs.Turns = []Turn{TurnNone}
+	if cs.RunUntilCrashes(1, 1000) {
+		t.Errorf("unexpected crash %v", cs.Crashes[0])
+	}
+}
+
+func TestRender(t *testing.T) {
+	lines, err := util.ReadLinesFromFile("input_test2.txt")
+	util.Check(err)
+	cs := NewCartSystem(lines)
+	if cs.String() != strings.Join(lines, "\n")+"\n" {
+		t.Errorf("expected input, got:\n%s", cs.String())
+	}
+
+	opts := DefaultGIFOptions()
+	opts.Crop = true
+	buf := bytes.Buffer{}
+	util.Check(cs.WriteGIF(&buf, opts))
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start, and after each tick up to the last crash at tick 3
+	if len(anim.Image) != 4 || cs.Time != 0 {
+		t.Errorf("expected 4 frames without changing the carts, got %d", len(anim.Image))
+	}
+
+	cs.RunUntilRemaining(1, math.MaxInt32)
+	expected := []string{
+		"/-X-\\  ",
+		"|   |  ",
+		"| /-+-\\",
+		"| | | |",
+		"\\-X-/ ^",
+		"  |   |",
+		"  \\---/",
+	}
+	if cs.String() != strings.Join(expected, "\n")+"\n" {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), cs.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	lines, err := util.ReadLinesFromFile("input.txt")
+	util.Check(err)
+	cs := NewCartSystem(lines)
+	if errs := cs.Validate(); len(errs) > 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+
+	broken := []string{
+		`/->-\  `,
+		`|   | /`,
+		`| /-+-/`,
+		`\-+-/  `,
+		`  \-v- `,
+	}
+	// The first line doesn't have to be the longest
+	ragged := []string{
+		`/-\`,
+		`| |  /---\`,
+		`\-/  \->-/`,
+	}
+	cs = NewCartSystem(ragged)
+	if errs := cs.Validate(); len(errs) > 0 || cs.Width != 10 || cs.Height != 3 {
+		t.Errorf("expected valid 10x3 tracks, got %dx%d with errors %v", cs.Width, cs.Height, errs)
+	}
+
+	cs = NewCartSystem(broken)
+	errs := cs.Validate()
+	expected := []string{
+		`6,1: corner '/' doesn't join up either way round`,
+		`6,2: '/' goes up into '/' at 6,1`,
+		`3,4: '-' goes right into '|' at 4,4`,
+		`4,4: under cart 1: '|' goes up into '/' at 4,3`,
+		`4,4: under cart 1: '|' goes down off the edge of the map`,
+		`5,4: '-' goes right into ' ' at 6,4`,
+		`5,4: '-' goes left into '|' at 4,4`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i := range errs {
+		if errs[i].Error() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], errs[i].Error())
+		}
+	}
+	if _, err := cs.Network(); err == nil {
+		t.Errorf("expected error for broken tracks")
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	lines, err := util.ReadLinesFromFile("input_test1.txt")
+	util.Check(err)
+	cs := NewCartSystem(lines)
+	n, err := cs.Network()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Intersections) != 4 || len(n.Segments) != 16 || len(n.Circuits) != 1 || n.Circuits[0].Tracks != 8 {
+		t.Errorf("unexpected network %+v", n)
+	}
+	for i, s := range n.Segments {
+		r := n.Segments[s.Reverse]
+		if r.Reverse != i || r.Track != s.Track || r.Length() != s.Length() {
+			t.Errorf("segment %d doesn't match its reverse %d", i, s.Reverse)
+		}
+	}
+
+	// Predicting crashes matches ticking until there's one cart left
+	for _, file := range []string{"input_test1.txt", "input_test2.txt", "input.txt"} {
+		lines, err := util.ReadLinesFromFile(file)
+		util.Check(err)
+		cs := NewCartSystem(lines)
+		n, err := cs.Network()
+		util.Check(err)
+		ticked := cs.Clone()
+		ticked.RunUntilRemaining(1, 100000)
+		predicted := n.PredictCrashes(&cs, ticked.Time)
+		if len(predicted) != len(ticked.Crashes) {
+			t.Fatalf("%s: expected %v, got %v", file, ticked.Crashes, predicted)
+		}
+		for i := range predicted {
+			if predicted[i] != ticked.Crashes[i] {
+				t.Errorf("%s: expected %v, got %v", file, ticked.Crashes[i], predicted[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package day13
+
+import (
+	"errors"
+	"fmt"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"sort"
+)
+
+// Directions out of a square, in the same order as the connect* bits
+var directions = [4]util.Vec2D{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+var directionNames = [4]string{"up", "right", "down", "left"}
+
+// Which directions a square of track joins up with its neighbours in
+const (
+	connectUp uint8 = 1 << iota
+	connectRight
+	connectDown
+	connectLeft
+)
+
+// Ways each kind of track could join up: a corner could turn either way
+var trackConnections = map[byte][]uint8{
+	TrackH:    {connectLeft | connectRight},
+	TrackV:    {connectUp | connectDown},
+	Intersect: {connectUp | connectRight | connectDown | connectLeft},
+	CornerR:   {connectRight | connectDown, connectUp | connectLeft},
+	CornerL:   {connectLeft | connectDown, connectUp | connectRight},
+}
+
+func opposite(d int) int {
+	return (d + 2) % 4
+}
+
+// The direction in connections other than d, for a square with exactly 2 connections
+func otherDirection(connections uint8, d int) int {
+	for i := range directions {
+		if i != d && connections&(1<<i) != 0 {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("no other direction than %v in %04b", directionNames[d], connections))
+}
+
+// A place where the tracks don't join up
+type TrackError struct {
+	Position util.Vec2D
+	Message  string
+}
+
+func (e TrackError) Error() string {
+	return fmt.Sprintf("%d,%d: %s", e.Position.X, e.Position.Y, e.Message)
+}
+
+/*
+joints works out which directions each square of track joins up in, choosing
+for each corner whichever way round fits with its neighbours, and finds
+anywhere that doesn't join up.
+*/
+func (cs *CartSystem) joints() (util.Grid[uint8], []TrackError) {
+	joints := util.NewGrid[uint8](cs.Tracks.Width(), cs.Tracks.Height())
+	errs := make([]TrackError, 0)
+	corners := make([]util.Vec2D, 0)
+	cs.Tracks.Traverse(func(p util.Vec2D, track *byte) {
+		options, ok := trackConnections[*track]
+		switch {
+		case *track == ' ':
+		case !ok:
+			errs = append(errs, TrackError{p, fmt.Sprintf("unknown track %q", *track)})
+		case len(options) > 1:
+			corners = append(corners, p)
+		default:
+			joints.Set(p, options[0])
+		}
+	})
+
+	// Could the track at p join up in direction d? Corners not worked out yet could go either way.
+	canJoin := func(p util.Vec2D, d int) bool {
+		if !cs.Tracks.Valid(p) {
+			return false
+		}
+		if j := joints.Get(p); j != 0 {
+			return j&(1<<d) != 0
+		}
+		return len(trackConnections[cs.Tracks.Get(p)]) > 1
+	}
+	// Each corner worked out can help with its neighbours, so keep going until stuck
+	for progress := true; progress; {
+		progress = false
+		remaining := corners[:0]
+		for _, p := range corners {
+			fits := make([]uint8, 0)
+			for _, option := range trackConnections[cs.Tracks.Get(p)] {
+				ok := true
+				for d := range directions {
+					if option&(1<<d) != 0 && !canJoin(p.Add(directions[d]), opposite(d)) {
+						ok = false
+					}
+				}
+				if ok {
+					fits = append(fits, option)
+				}
+			}
+			switch len(fits) {
+			case 0:
+				errs = append(errs, TrackError{p, fmt.Sprintf("corner %q doesn't join up either way round", cs.Tracks.Get(p))})
+				progress = true
+			case 1:
+				joints.Set(p, fits[0])
+				progress = true
+			default:
+				remaining = append(remaining, p)
+			}
+		}
+		corners = remaining
+	}
+	for _, p := range corners {
+		errs = append(errs, TrackError{p, fmt.Sprintf("can't tell which way corner %q turns", cs.Tracks.Get(p))})
+	}
+
+	// Every joint has to be matched by the neighbour it joins up with
+	joints.Traverse(func(p util.Vec2D, j *uint8) {
+		for d := range directions {
+			if *j&(1<<d) == 0 {
+				continue
+			}
+			q := p.Add(directions[d])
+			if !joints.Valid(q) {
+				errs = append(errs, TrackError{p, fmt.Sprintf("%q goes %s off the edge of the map", cs.Tracks.Get(p), directionNames[d])})
+			} else if joints.Get(q)&(1<<opposite(d)) == 0 {
+				errs = append(errs, TrackError{p, fmt.Sprintf("%q goes %s into %q at %d,%d", cs.Tracks.Get(p), directionNames[d], cs.Tracks.Get(q), q.X, q.Y)})
+			}
+		}
+	})
+
+	// Tracks under carts came from the carts' directions, so say so
+	for i := range errs {
+		for id, t := range cs.trajectories {
+			if t[0] == errs[i].Position {
+				errs[i].Message = fmt.Sprintf("under cart %d: %s", id, errs[i].Message)
+			}
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Position.ReadingLess(errs[j].Position)
+	})
+	return joints, errs
+}
+
+// Check that every piece of track joins up with its neighbours, returning anywhere it doesn't
+func (cs *CartSystem) Validate() []TrackError {
+	_, errs := cs.joints()
+	return errs
+}
+
+/*
+Segment is a stretch of track from one intersection to the next, in one
+direction, or all the way round a loop with no intersections.
+*/
+type Segment struct {
+	// Squares from the start to the end, inclusive
+	Path []util.Vec2D
+	// Index of the same stretch of track in the opposite direction
+	Reverse int
+	// Identifies the stretch of track, whichever direction it's in
+	Track int
+}
+
+// Number of ticks to get from one end to the other
+func (s *Segment) Length() int {
+	return len(s.Path) - 1
+}
+
+// Direction of travel when arriving at the end
+func (s *Segment) EndDirection() util.Vec2D {
+	return s.Path[len(s.Path)-1].Sub(s.Path[len(s.Path)-2])
+}
+
+// Does the segment go round a loop with no intersections?
+func (s *Segment) IsLoop() bool {
+	return s.Path[0] == s.Path[len(s.Path)-1]
+}
+
+// Tracks that join up with each other, but not with any other tracks
+type Circuit struct {
+	// Number of squares of track
+	Size          int
+	Intersections []util.Vec2D
+	// Number of stretches of track, i.e. segments in one direction
+	Tracks int
+}
+
+// Leaving square p in direction d
+type step struct {
+	p, d util.Vec2D
+}
+
+// Somewhere along a segment
+type place struct {
+	segment, offset int
+}
+
+// The tracks as a graph, with intersections as nodes and segments as edges
+type Network struct {
+	Intersections []util.Vec2D
+	Segments      []Segment
+	Circuits      []Circuit
+	along         map[step]place
+}
+
+// Work out the graph of the tracks, which must be valid
+func (cs *CartSystem) Network() (*Network, error) {
+	joints, errs := cs.joints()
+	if len(errs) > 0 {
+		all := make([]error, len(errs))
+		for i := range errs {
+			all[i] = errs[i]
+		}
+		return nil, errors.Join(all...)
+	}
+
+	n := &Network{along: make(map[step]place)}
+	walk := func(start util.Vec2D, d int) {
+		path := []util.Vec2D{start}
+		for p := start; ; {
+			p = p.Add(directions[d])
+			path = append(path, p)
+			if cs.Tracks.Get(p) == Intersect || p == start {
+				break
+			}
+			// Carry on out of the other side of the square
+			d = otherDirection(joints.Get(p), opposite(d))
+		}
+		for i := 0; i < len(path)-1; i++ {
+			n.along[step{path[i], path[i+1].Sub(path[i])}] = place{len(n.Segments), i}
+		}
+		n.Segments = append(n.Segments, Segment{Path: path})
+	}
+	cs.Tracks.Traverse(func(p util.Vec2D, track *byte) {
+		if *track == Intersect {
+			n.Intersections = append(n.Intersections, p)
+			for d := range directions {
+				walk(p, d)
+			}
+		}
+	})
+	// Anything not visited yet must be a loop without intersections
+	joints.Traverse(func(p util.Vec2D, j *uint8) {
+		if *j == 0 || cs.Tracks.Get(p) == Intersect {
+			return
+		}
+		for d := range directions {
+			if *j&(1<<d) != 0 {
+				if _, ok := n.along[step{p, directions[d]}]; !ok {
+					walk(p, d)
+				}
+			}
+		}
+	})
+
+	circuits := util.NewUnionFind[util.Vec2D]()
+	for i := range n.Segments {
+		s := &n.Segments[i]
+		end := s.Path[len(s.Path)-1]
+		s.Reverse = n.along[step{end, s.EndDirection().Scale(-1)}].segment
+		s.Track = util.MinInt(i, s.Reverse)
+		for _, p := range s.Path {
+			circuits.Add(p)
+			circuits.Union(s.Path[0], p)
+		}
+	}
+	groups := circuits.Groups()
+	roots := make([]util.Vec2D, 0, len(groups))
+	for root := range groups {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return util.BoxAround(groups[roots[i]]...).Min.ReadingLess(util.BoxAround(groups[roots[j]]...).Min)
+	})
+	index := make(map[util.Vec2D]int)
+	for i, root := range roots {
+		n.Circuits = append(n.Circuits, Circuit{Size: len(groups[root])})
+		index[root] = i
+	}
+	for _, p := range n.Intersections {
+		c := &n.Circuits[index[circuits.Find(p)]]
+		c.Intersections = append(c.Intersections, p)
+	}
+	for i, s := range n.Segments {
+		if s.Track == i {
+			n.Circuits[index[circuits.Find(s.Path[0])]].Tracks++
+		}
+	}
+	return n, nil
+}
+
+// A cart travelling along a segment, from Path[Offset] at tick Start to the end
+type Visit struct {
+	Segment int
+	Offset  int
+	Start   int
+}
+
+// Tick when the cart gets to the end of the segment
+func (n *Network) End(v Visit) int {
+	return v.Start + n.Segments[v.Segment].Length() - v.Offset
+}
+
+/*
+Schedule works out where cart goes from tick start until at least tick until,
+a segment at a time, assuming that it never crashes.
+*/
+func (n *Network) Schedule(cart Cart, turns []Turn, start, until int) []Visit {
+	first, ok := n.along[step{cart.Position, cart.Velocity}]
+	if !ok {
+		panic(fmt.Sprintf("cart %d is off the track at %v", cart.Id, cart.Position))
+	}
+	visits := []Visit{{first.segment, first.offset, start}}
+	for end := n.End(visits[0]); end < until; end = n.End(visits[len(visits)-1]) {
+		s := &n.Segments[visits[len(visits)-1].Segment]
+		if s.IsLoop() {
+			visits = append(visits, Visit{visits[len(visits)-1].Segment, 0, end})
+			continue
+		}
+		// Turn at the intersection the same way as Tick would
+		cart.Previous = s.Path[len(s.Path)-2]
+		cart.Position = s.Path[len(s.Path)-1]
+		cart.Velocity = s.EndDirection()
+		cart.HandleIntersection(turns)
+		visits = append(visits, Visit{n.along[step{cart.Position, cart.Velocity}].segment, 0, end})
+	}
+	return visits
+}
+
+// Where a cart following visits is at tick t
+func (n *Network) PositionAt(visits []Visit, t int) util.Vec2D {
+	i := sort.Search(len(visits), func(i int) bool {
+		return visits[i].Start > t
+	}) - 1
+	v := visits[i]
+	return n.Segments[v.Segment].Path[v.Offset+t-v.Start]
+}
+
+/*
+PredictCrashes works out the crashes that Tick would find with
+CollisionRemove, from now until tick until, without ticking. Each cart's route
+is worked out a segment at a time, and then only carts on the same stretch of
+track (or at the same intersection) at around the same time need to be checked
+against each other tick by tick.
+*/
+func (n *Network) PredictCrashes(cs *CartSystem, until int) []Crash {
+	type interval struct {
+		cart     int
+		from, to int
+	}
+	routes := make(map[int][]Visit)
+	// Times each cart is on each stretch of track, and at each intersection
+	tracks := make(map[int][]interval)
+	intersections := make(map[util.Vec2D][]interval)
+	for _, c := range cs.Carts {
+		routes[c.Id] = n.Schedule(c, cs.Turns, cs.Time, until)
+		for _, v := range routes[c.Id] {
+			s := &n.Segments[v.Segment]
+			end := n.End(v)
+			tracks[s.Track] = append(tracks[s.Track], interval{c.Id, v.Start, end})
+			if !s.IsLoop() {
+				p := s.Path[len(s.Path)-1]
+				intersections[p] = append(intersections[p], interval{c.Id, end, end})
+			}
+		}
+	}
+
+	// Pairs of carts that might meet, and the ticks when they might
+	type candidate struct {
+		a, b, tick int
+	}
+	candidates := util.NewSet[candidate]()
+	findCandidates := func(intervals []interval) {
+		sort.Slice(intervals, func(i, j int) bool {
+			return intervals[i].from < intervals[j].from
+		})
+		for i, x := range intervals {
+			for _, y := range intervals[i+1:] {
+				if y.from > x.to+1 {
+					break
+				}
+				if x.cart == y.cart {
+					continue
+				}
+				for t := util.MaxInt(x.from, y.from); t <= util.MinInt(x.to, y.to)+1; t++ {
+					candidates.Add(candidate{util.MinInt(x.cart, y.cart), util.MaxInt(x.cart, y.cart), t})
+				}
+			}
+		}
+	}
+	for _, intervals := range tracks {
+		findCandidates(intervals)
+	}
+	for _, intervals := range intersections {
+		findCandidates(intervals)
+	}
+
+	// Carts meet when one moves onto the other, whether or not the other has moved yet
+	type meeting struct {
+		Crash
+		// Where the moving cart started the tick, which decides the order crashes happen in
+		from util.Vec2D
+	}
+	meetings := make([]meeting, 0)
+	for _, c := range candidates.Items() {
+		if c.tick <= cs.Time || c.tick > until {
+			continue
+		}
+		a0, b0 := n.PositionAt(routes[c.a], c.tick-1), n.PositionAt(routes[c.b], c.tick-1)
+		a1, b1 := n.PositionAt(routes[c.a], c.tick), n.PositionAt(routes[c.b], c.tick)
+		first, second := c.a, c.b
+		if b0.ReadingLess(a0) {
+			first, second = c.b, c.a
+			a0, b0, a1, b1 = b0, a0, b1, a1
+		}
+		switch {
+		case a1 == b0:
+			meetings = append(meetings, meeting{Crash{c.tick, a1, [2]int{first, second}, CollisionRemove}, a0})
+		case b1 == a1:
+			meetings = append(meetings, meeting{Crash{c.tick, b1, [2]int{second, first}, CollisionRemove}, b0})
+		}
+	}
+	sort.Slice(meetings, func(i, j int) bool {
+		if meetings[i].Tick != meetings[j].Tick {
+			return meetings[i].Tick < meetings[j].Tick
+		}
+		if meetings[i].from != meetings[j].from {
+			return meetings[i].from.ReadingLess(meetings[j].from)
+		}
+		return meetings[i].Carts[1] < meetings[j].Carts[1]
+	})
+
+	// Carts that crash are removed, so can't meet anything afterwards
+	result := make([]Crash, 0)
+	removed := util.NewSet[int]()
+	for _, m := range meetings {
+		if !removed.Contains(m.Carts[0]) && !removed.Contains(m.Carts[1]) {
+			result = append(result, m.Crash)
+			removed.Add(m.Carts[0])
+			removed.Add(m.Carts[1])
+		}
+	}
+	return result
+}
@@ -0,0 +1,206 @@
+package day13
+
+import (
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"slices"
+)
+
+// Marks where carts have crashed
+const CrashSite = 'X'
+
+// Character for the cart in the map, i.e. which way it's going
+func (c *Cart) Glyph() byte {
+	switch c.Velocity {
+	case util.Vec2D{0, -1}:
+		return CartU
+	case util.Vec2D{0, 1}:
+		return CartD
+	case util.Vec2D{-1, 0}:
+		return CartL
+	default:
+		return CartR
+	}
+}
+
+/*
+Clone creates a copy of the cart system which can carry on independently.
+Tracks are shared, because they never change.
+*/
+func (cs *CartSystem) Clone() CartSystem {
+	c := *cs
+	c.Carts = slices.Clone(cs.Carts)
+	c.Crashes = slices.Clone(cs.Crashes)
+	c.trajectories = make([][]util.Vec2D, len(cs.trajectories))
+	for i, t := range cs.trajectories {
+		c.trajectories[i] = slices.Clone(t)
+	}
+	return c
+}
+
+// Area covered by the tracks
+func (cs *CartSystem) Bounds() util.Box2D {
+	return util.Box2D{Min: cs.Tracks.Min(), Max: cs.Tracks.Max()}
+}
+
+// Renderer for the tracks, with crash sites and then carts drawn on top
+func (cs *CartSystem) Renderer() util.GridRenderer[byte] {
+	crashes := make([]util.Vec2D, 0, len(cs.Crashes))
+	for _, c := range cs.Crashes {
+		crashes = append(crashes, c.Position)
+	}
+	carts := make(map[util.Vec2D]byte)
+	for i := range cs.Carts {
+		carts[cs.Carts[i].Position] = cs.Carts[i].Glyph()
+	}
+	return util.GridRenderer[byte]{
+		Glyph: util.ByteGlyph,
+		Layers: []util.GridLayer{
+			util.PointsLayer(crashes, CrashSite, util.AnsiRed),
+			util.EntityLayer(carts, util.AnsiYellow),
+		},
+	}
+}
+
+func (cs *CartSystem) String() string {
+	return cs.RenderBox(cs.Bounds())
+}
+
+// Draw the part of the tracks inside box
+func (cs *CartSystem) RenderBox(box util.Box2D) string {
+	r := cs.Renderer()
+	sub := cs.Tracks.SubGrid(box.Min, box.Max)
+	return r.Render(&sub)
+}
+
+// Limit box to the area covered by the tracks
+func (cs *CartSystem) clip(box util.Box2D) util.Box2D {
+	bounds := cs.Bounds()
+	return util.Box2D{Min: box.Min.Max(bounds.Min), Max: box.Max.Min(bounds.Max)}
+}
+
+type GIFOptions struct {
+	// Only draw every FrameSkip'th tick (and the last one)
+	FrameSkip int
+	// Only draw the area the carts visit, instead of all the tracks
+	Crop bool
+	// Pixels along each side of a square
+	Scale int
+	// Time between frames, in 100ths of a second
+	Delay int
+	// Give up looking for the last crash after this many ticks, e.g. if carts bounce forever
+	MaxTicks int
+}
+
+func DefaultGIFOptions() GIFOptions {
+	return GIFOptions{FrameSkip: 1, Crop: false, Scale: 3, Delay: 5, MaxTicks: 100000}
+}
+
+var gifPalette = color.Palette{
+	color.Black,
+	color.Gray{0x80},
+	color.RGBA{0xff, 0xd7, 0x00, 0xff},
+	color.RGBA{0xff, 0x20, 0x20, 0xff},
+}
+
+const (
+	gifBackground = iota
+	gifTrack
+	gifCart
+	gifCrash
+)
+
+/*
+WriteGIF writes an animation of the carts from now until the last crash (or
+until one cart is left, or MaxTicks), without changing cs.
+*/
+func (cs *CartSystem) WriteGIF(w io.Writer, opts GIFOptions) error {
+	// Find out how long to animate for, and where the carts go
+	preview := cs.Clone()
+	preview.RunUntilRemaining(1, opts.MaxTicks)
+	end := preview.Time
+	if len(preview.Crashes) > len(cs.Crashes) {
+		end = preview.Crashes[len(preview.Crashes)-1].Tick
+	}
+	box := cs.Bounds()
+	if opts.Crop {
+		box = util.EmptyBox[util.Vec2D]()
+		for _, t := range preview.trajectories {
+			// Only the part of the trajectory that gets drawn
+			for _, p := range t[util.MinInt(len(t), cs.Time):util.MinInt(len(t), end+1)] {
+				box.Extend(p)
+			}
+		}
+		if box.Empty() {
+			box = cs.Bounds()
+		}
+		box = cs.clip(box.Grow(util.Vec2D{1, 1}))
+	}
+	skip := util.MaxInt(opts.FrameSkip, 1)
+
+	anim := gif.GIF{}
+	sim := cs.Clone()
+	for {
+		if (sim.Time-cs.Time)%skip == 0 || sim.Time == end {
+			anim.Image = append(anim.Image, sim.Image(box, opts.Scale))
+			anim.Delay = append(anim.Delay, opts.Delay)
+		}
+		if sim.Time >= end {
+			break
+		}
+		sim.Tick()
+	}
+	// Linger on the last frame
+	anim.Delay[len(anim.Delay)-1] = 100
+	return gif.EncodeAll(w, &anim)
+}
+
+// Draw the part of the tracks inside box, with scale pixels per square
+func (cs *CartSystem) Image(box util.Box2D, scale int) *image.Paletted {
+	size := box.Size()
+	img := image.NewPaletted(image.Rect(0, 0, size.X*scale, size.Y*scale), gifPalette)
+	// Pixels of the square at p for which include returns true
+	fill := func(p util.Vec2D, index uint8, include func(x, y int) bool) {
+		origin := p.Sub(box.Min).Scale(scale)
+		for y := 0; y < scale; y++ {
+			for x := 0; x < scale; x++ {
+				if include(x, y) {
+					img.SetColorIndex(origin.X+x, origin.Y+y, index)
+				}
+			}
+		}
+	}
+	mid := scale / 2
+
+	for y := box.Min.Y; y <= box.Max.Y; y++ {
+		for x := box.Min.X; x <= box.Max.X; x++ {
+			p := util.Vec2D{x, y}
+			switch cs.Tracks.Get(p) {
+			case TrackH:
+				fill(p, gifTrack, func(x, y int) bool { return y == mid })
+			case TrackV:
+				fill(p, gifTrack, func(x, y int) bool { return x == mid })
+			case Intersect:
+				fill(p, gifTrack, func(x, y int) bool { return x == mid || y == mid })
+			case CornerR:
+				fill(p, gifTrack, func(x, y int) bool { return x+y == scale-1 })
+			case CornerL:
+				fill(p, gifTrack, func(x, y int) bool { return x == y })
+			}
+		}
+	}
+	for _, c := range cs.Crashes {
+		if box.Contains(c.Position) {
+			fill(c.Position, gifCrash, func(x, y int) bool { return x == y || x+y == scale-1 })
+		}
+	}
+	for _, c := range cs.Carts {
+		if box.Contains(c.Position) {
+			fill(c.Position, gifCart, func(x, y int) bool { return true })
+		}
+	}
+	return img
+}
@@ -4,10 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 )
 
-func part1impl(logger *log.Logger, previous int, slice int) []byte {
+func part1impl(logger *util.Logger, previous int, slice int) []byte {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -43,7 +42,7 @@ func part1impl(logger *log.Logger, previous int, slice int) []byte {
 	return recipes[previous : previous+slice]
 }
 
-func part2impl(logger *log.Logger, match []byte) int {
+func part2impl(logger *util.Logger, match []byte) int {
 	t := util.NewTimer(logger, "")
 	defer t.LogCheckpoint("end")
 
@@ -93,7 +92,7 @@ func part2impl(logger *log.Logger, match []byte) int {
 	return matchStart
 }
 
-func part1(logger *log.Logger, previous int, slice int) string {
+func part1(logger *util.Logger, previous int, slice int) string {
 	result := part1impl(logger, previous, slice)
 	for i := range result {
 		result[i] += '0'
@@ -101,7 +100,7 @@ func part1(logger *log.Logger, previous int, slice int) string {
 	return string(result)
 }
 
-func part2(logger *log.Logger, input int) string {
+func part2(logger *util.Logger, input int) string {
 	match := []byte(fmt.Sprint(input))
 	for i := range match {
 		match[i] -= '0'
@@ -111,11 +110,11 @@ func part2(logger *log.Logger, input int) string {
 }
 
 func init() {
-	util.RegisterSolution("day14part1", func(logger *log.Logger) string {
+	util.RegisterSolution("day14part1", func(logger *util.Logger) string {
 		return part1(logger, 635041, 10)
 	})
 
-	util.RegisterSolution("day14part2", func(logger *log.Logger) string {
+	util.RegisterSolution("day14part2", func(logger *util.Logger) string {
 		return part2(logger, 635041)
 	})
 }
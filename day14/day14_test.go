@@ -1,13 +1,13 @@
 package day14
 
 import (
-	"log"
+	"github.com/alanbriolat/AdventOfCode2018/util"
 	"os"
 	"testing"
 )
 
 func TestPart1Impl(t *testing.T) {
-	logger := log.New(os.Stdout, "", 0)
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
 	tables := []struct{
 		previous int
 		slice int
@@ -28,7 +28,7 @@ func TestPart1Impl(t *testing.T) {
 }
 
 func TestPart2Impl(t *testing.T) {
-	logger := log.New(os.Stdout, "", 0)
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
 	tables := []struct{
 		match []byte
 		result int
@@ -0,0 +1,260 @@
+package day15
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// What one unit did over the course of a battle
+type UnitStats struct {
+	Name    string
+	Faction int
+	StartHP int
+	// Remaining hit points, 0 or less if the unit died
+	HitPoints   int
+	DamageDealt int
+	DamageTaken int
+	Kills       int
+	// Name of the unit that dealt the killing blow, if the unit died
+	KilledBy string
+	// Number of squares moved
+	Distance int
+	// Round of the first attack the unit made or received, 0 if it never fought
+	FirstContact int
+}
+
+func (s *UnitStats) IsAlive() bool {
+	return s.HitPoints > 0
+}
+
+// Total hit points of each faction after a round
+type RoundStats struct {
+	Round     int
+	FactionHP []int
+}
+
+/*
+Analytics collects statistics about a battle from its events, either as it's
+fought (see Attach) or afterwards from a Recording (see AnalyseRecording).
+*/
+type Analytics struct {
+	Rules Rules
+	// Every unit, in the order they were created
+	Units []*UnitStats
+	// Every round, including the round where combat ended
+	Rounds []RoundStats
+	// Rounds started so far
+	Round int
+	// Did combat end with one side having no targets?
+	Ended bool
+	units map[string]*UnitStats
+	// Attacker of the most recent attack, to credit the kill
+	lastAttacker string
+}
+
+// Collect statistics for b, which must not have started yet
+func NewAnalytics(b *Battle) *Analytics {
+	a := &Analytics{Rules: b.Rules, units: make(map[string]*UnitStats)}
+	for _, u := range b.Units {
+		s := &UnitStats{Name: u.Name(), Faction: u.Faction, StartHP: u.HitPoints, HitPoints: u.HitPoints}
+		a.Units = append(a.Units, s)
+		a.units[s.Name] = s
+	}
+	return a
+}
+
+// Collect statistics while b is fought, as well as calling any existing OnEvent
+func (a *Analytics) Attach(b *Battle) {
+	next := b.OnEvent
+	b.OnEvent = func(e Event) {
+		a.Record(e)
+		if next != nil {
+			next(e)
+		}
+	}
+}
+
+// Collect statistics from a recorded battle
+func AnalyseRecording(r *Recording) *Analytics {
+	b := NewBattleWithRules(r.Input, r.Rules)
+	a := NewAnalytics(&b)
+	for _, e := range r.Events {
+		a.Record(e)
+	}
+	return a
+}
+
+func (a *Analytics) unit(name string) *UnitStats {
+	s, ok := a.units[name]
+	if !ok {
+		// Battles and ReadRecording only produce events for known units
+		panic(fmt.Sprintf("unknown unit %q in battle events", name))
+	}
+	return s
+}
+
+// Use as Battle.OnEvent
+func (a *Analytics) Record(e Event) {
+	switch e.Type {
+	case EventRound:
+		if e.Round > 1 {
+			a.endRound(e.Round - 1)
+		}
+		a.Round = e.Round
+	case EventMove:
+		a.unit(e.Unit).Distance++
+	case EventAttack:
+		attacker, target := a.unit(e.Unit), a.unit(e.Target)
+		// Damage beyond the target's remaining hit points is wasted
+		damage := e.Damage + util.MinInt(e.HitPoints, 0)
+		attacker.DamageDealt += damage
+		target.DamageTaken += damage
+		target.HitPoints = e.HitPoints
+		for _, s := range []*UnitStats{attacker, target} {
+			if s.FirstContact == 0 {
+				s.FirstContact = e.Round
+			}
+		}
+		a.lastAttacker = e.Unit
+	case EventDeath:
+		a.unit(e.Unit).KilledBy = a.lastAttacker
+		a.unit(a.lastAttacker).Kills++
+	case EventEnd:
+		a.endRound(e.Round)
+		a.Ended = true
+	}
+}
+
+func (a *Analytics) endRound(round int) {
+	a.Rounds = append(a.Rounds, RoundStats{round, a.FactionHP()})
+}
+
+// Current total hit points of each faction
+func (a *Analytics) FactionHP() []int {
+	result := make([]int, len(a.Rules.Factions))
+	for _, s := range a.Units {
+		if s.IsAlive() {
+			result[s.Faction] += s.HitPoints
+		}
+	}
+	return result
+}
+
+// Factions with units still alive
+func (a *Analytics) Survivors() []string {
+	result := make([]string, 0)
+	for i, hp := range a.FactionHP() {
+		if hp > 0 {
+			result = append(result, a.Rules.Factions[i].Name)
+		}
+	}
+	return result
+}
+
+// Write a row for each unit, with a header row
+func (a *Analytics) WriteUnitsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"unit", "faction", "start_hp", "hp", "damage_dealt", "damage_taken", "kills", "killed_by", "distance", "first_contact"})
+	for _, s := range a.Units {
+		cw.Write([]string{
+			s.Name,
+			a.Rules.Factions[s.Faction].Name,
+			strconv.Itoa(s.StartHP),
+			strconv.Itoa(util.MaxInt(s.HitPoints, 0)),
+			strconv.Itoa(s.DamageDealt),
+			strconv.Itoa(s.DamageTaken),
+			strconv.Itoa(s.Kills),
+			s.KilledBy,
+			strconv.Itoa(s.Distance),
+			strconv.Itoa(s.FirstContact),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Write a row for each round with the total hit points of each faction, with a header row
+func (a *Analytics) WriteRoundsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"round"}
+	for _, f := range a.Rules.Factions {
+		header = append(header, f.Name+"_hp")
+	}
+	cw.Write(header)
+	for _, r := range a.Rounds {
+		row := []string{strconv.Itoa(r.Round)}
+		for _, hp := range r.FactionHP {
+			row = append(row, strconv.Itoa(hp))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+/*
+Summary describes the outcome of the battle and how each faction fared, e.g.
+
+	combat ended in round 19, goblin won, outcome 18x1546 = 27828
+	faction   units  alive     hp  damage  kills  moved  first contact
+	elf           1      0      0      54      0      1              1
+	goblin        8      8   1546     200      1     21              1
+	most damage: G1 (54), most kills: G3 (1), furthest moved: G0 (3)
+*/
+func (a *Analytics) Summary() string {
+	sb := strings.Builder{}
+	survivors := a.Survivors()
+	if a.Ended {
+		hp := 0
+		for _, h := range a.FactionHP() {
+			hp += h
+		}
+		fmt.Fprintf(&sb, "combat ended in round %d, %s won, outcome %dx%d = %d\n", a.Round, strings.Join(survivors, " and "), a.Round-1, hp, (a.Round-1)*hp)
+	} else {
+		fmt.Fprintf(&sb, "combat stopped in round %d, %s still fighting\n", a.Round, strings.Join(survivors, " and "))
+	}
+
+	fmt.Fprintf(&sb, "%-8s %6s %6s %6s %7s %6s %6s %14s\n", "faction", "units", "alive", "hp", "damage", "kills", "moved", "first contact")
+	for i, f := range a.Rules.Factions {
+		units, alive, hp, damage, kills, moved, contact := 0, 0, 0, 0, 0, 0, 0
+		for _, s := range a.Units {
+			if s.Faction != i {
+				continue
+			}
+			units++
+			if s.IsAlive() {
+				alive++
+				hp += s.HitPoints
+			}
+			damage += s.DamageDealt
+			kills += s.Kills
+			moved += s.Distance
+			if s.FirstContact > 0 && (contact == 0 || s.FirstContact < contact) {
+				contact = s.FirstContact
+			}
+		}
+		fmt.Fprintf(&sb, "%-8s %6d %6d %6d %7d %6d %6d %14d\n", f.Name, units, alive, hp, damage, kills, moved, contact)
+	}
+
+	best := func(value func(s *UnitStats) int) string {
+		var result *UnitStats
+		for _, s := range a.Units {
+			if result == nil || value(s) > value(result) {
+				result = s
+			}
+		}
+		if result == nil {
+			return "none"
+		}
+		return fmt.Sprintf("%s (%d)", result.Name, value(result))
+	}
+	fmt.Fprintf(&sb, "most damage: %s, most kills: %s, furthest moved: %s\n",
+		best(func(s *UnitStats) int { return s.DamageDealt }),
+		best(func(s *UnitStats) int { return s.Kills }),
+		best(func(s *UnitStats) int { return s.Distance }))
+	return sb.String()
+}
@@ -1,77 +1,87 @@
 package day15
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"math"
 	"os"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 )
 
 const (
-	InputWall   = '#'
-	InputFloor  = '.'
-	InputElf    = 'E'
-	InputGoblin = 'G'
-	MapWall     = 254
-	MapFloor    = 255
+	InputWall    = '#'
+	InputFloor   = '.'
+	InputElf     = 'E'
+	InputGoblin  = 'G'
+	InputOutside = ' ' // Past the end of a line
+	MapWall      = 254
+	MapFloor     = 255
 )
 
-func tieBreak(p1, p2 util.Vec2D) bool {
-	return p1.Y < p2.Y || (p1.Y == p2.Y && p1.X < p2.X)
+type BitMap struct {
+	util.Grid[bool]
 }
 
-type BitMap [][]bool
+func NewBitMap(size util.Vec2D) BitMap {
+	return BitMap{util.NewGrid[bool](size.X, size.Y)}
+}
 
 func (b BitMap) String() string {
-	sb := strings.Builder{}
-	sb.Grow(len(b) * (len((b)[0]) + 1))
-	for y := 0; y < len(b); y++ {
-		for x := 0; x < len(b[0]); x++ {
-			switch b[x][y] {
-			case true:
-				sb.WriteByte('*')
-			case false:
-				sb.WriteByte('_')
+	r := util.GridRenderer[bool]{
+		Glyph: func(p util.Vec2D, set bool) byte {
+			if set {
+				return '*'
+			} else {
+				return '_'
 			}
-		}
-		sb.WriteByte('\n')
+		},
 	}
-	return sb.String()
-}
-
-func (b BitMap) Get(p util.Vec2D) bool {
-	return b[p.X][p.Y]
+	return r.Render(&b.Grid)
 }
 
 func (b BitMap) Set(p util.Vec2D) {
-	b[p.X][p.Y] = true
+	b.Grid.Set(p, true)
 }
 
 func (b BitMap) Unset(p util.Vec2D) {
-	b[p.X][p.Y] = false
+	b.Grid.Set(p, false)
 }
 
 type Unit struct {
-	Battle        *Battle
-	Id            byte
-	Position      util.Vec2D
-	IsGoblin      bool
-	HitPoints     int
-	AttackPower   int
-	reachable     BitMap
-	reachableFrom util.Vec2D
+	Battle *Battle
+	Id     byte
+	// Order of creation, which unlike Id never changes
+	Serial   int
+	Position util.Vec2D
+	// Index into the battle's Rules.Factions
+	Faction     int
+	HitPoints   int
+	AttackPower int
+	name        string
 }
 
 func (u *Unit) String() string {
-	unitType := InputElf
-	if u.IsGoblin {
-		unitType = InputGoblin
-	}
-	return fmt.Sprintf("%s%d(%d)@%d,%d", string(unitType), u.Id, u.HitPoints, u.Position.X, u.Position.Y)
+	return fmt.Sprintf("%c%d(%d)@%d,%d", u.Glyph(), u.Id, u.HitPoints, u.Position.X, u.Position.Y)
+}
+
+// Character for the unit in the map
+func (u *Unit) Glyph() byte {
+	return u.Battle.Rules.Factions[u.Faction].Glyph[0]
+}
+
+// Is the unit one of the faction named by Rules.Elves?
+func (u *Unit) IsElf() bool {
+	elves, ok := u.Battle.Rules.ElfFaction()
+	return ok && u.Faction == elves
+}
+
+// Name that identifies the unit for the whole battle, e.g. in events
+func (u *Unit) Name() string {
+	return u.name
 }
 
 func (u *Unit) IsAlive() bool {
@@ -79,46 +89,10 @@ func (u *Unit) IsAlive() bool {
 }
 
 func (u *Unit) IsEnemy(o *Unit) bool {
-	// Don't need to check for same ID, because the IsGoblin value would be the same
-	return u.IsGoblin != o.IsGoblin
+	// Don't need to check for same ID, because the faction would be the same
+	return u.Faction != o.Faction
 }
 
-/*
-Reachable creates a map of reachable squares from a Unit's position using
-a flood fill algorithm.
-*/
-func (u *Unit) Reachable() BitMap {
-	// Next squares to process - upperbound length is all non-wall squares
-	queue := make([]util.Vec2D, 0, u.Battle.NonWallCount)
-	// Squares already in the processing queue
-	queued := BitMap(util.NewBoolGrid(u.Battle.MapSize.X, u.Battle.MapSize.Y))
-	// Squares that are reachable from u.Position
-	reachable := BitMap(util.NewBoolGrid(u.Battle.MapSize.X, u.Battle.MapSize.Y))
-
-	// Start off with u.Position in the queue - can reach own position by not moving
-	queue = append(queue, u.Position)
-	queued[u.Position.X][u.Position.Y] = true
-
-	for len(queue) > 0 {
-		// Pop from the front of the queue
-		var next util.Vec2D
-		next, queue = queue[0], queue[1:]
-		// Mark as reachable
-		reachable.Set(next)
-		// Find candidates for other reachable squares
-		adjacent := u.Battle.Adjacent(next, true)
-		for _, p := range adjacent {
-			// Skip squares already processed or queued
-			if !queued.Get(p) {
-				queue = append(queue, p)
-				queued.Set(p)
-			}
-		}
-	}
-	return reachable
-}
-
-//go:generate genny -in=../util/astar.go -out=gen-astar.go -pkg=day15 gen "SearchNode=util.Vec2D"
 /*
 FindPath finds the shortest path to reach a destination, implemented as A*
 search.
@@ -134,7 +108,57 @@ The following details are specific to the requirements of this problem:
 Mostly just following the pseudocode at https://en.wikipedia.org/wiki/A*_search_algorithm
 */
 func (u *Unit) FindPath(destinations []util.Vec2D) ([]util.Vec2D, error) {
-	search := AStarSearchContext{
+	search := u.pathSearch(destinations)
+	result, _, err := util.AStarSearch(&search)
+	return result, err
+}
+
+/*
+FindMove finds where to move to get closer to the nearest of destinations,
+using a single search from the unit's position.
+
+The nearest destination is chosen first, using "reading order" to choose
+between destinations at the same distance, and then the first step of the
+shortest paths to that destination, again in "reading order". Returns the
+unit's own position if it's already at a destination, or false if no
+destination can be reached.
+*/
+func (u *Unit) FindMove(destinations []util.Vec2D) (util.Vec2D, bool) {
+	if len(destinations) == 0 {
+		return u.Position, false
+	}
+	search := u.pathSearch(destinations)
+	paths, err := util.AllShortestPaths(&search)
+	if err != nil {
+		return u.Position, false
+	}
+	chosen := paths.Destinations[0]
+	if chosen == u.Position {
+		return u.Position, true
+	}
+	return paths.FirstSteps(chosen)[0], true
+}
+
+/*
+FindPathFrames renders the progress of FindPath as a sequence of map views,
+one per square expanded by the search, with the search frontier shown as
+overlayChar.
+*/
+func (u *Unit) FindPathFrames(destinations []util.Vec2D, overlayChar byte) []string {
+	trace := util.SearchTrace[util.Vec2D]{}
+	search := u.pathSearch(destinations)
+	search.OnEvent = trace.Record
+	util.AStarSearch(&search)
+
+	frames := make([]string, 0)
+	trace.Replay(func(step int, open, closed []util.Vec2D) {
+		frames = append(frames, u.Battle.MapView(u.Battle.CreateOverlapFromPoints(open), overlayChar, false))
+	})
+	return frames
+}
+
+func (u *Unit) pathSearch(destinations []util.Vec2D) util.AStarSearchContext[util.Vec2D] {
+	return util.AStarSearchContext[util.Vec2D]{
 		Start:        u.Position,
 		Destinations: destinations,
 		NodeCountMax: u.Battle.NonWallCount,
@@ -142,55 +166,93 @@ func (u *Unit) FindPath(destinations []util.Vec2D) ([]util.Vec2D, error) {
 			return u.Battle.Adjacent(n, true)
 		},
 		Heuristic: func(n1, n2 util.Vec2D) int {
+			if u.Battle.Rules.Diagonal {
+				return n2.Sub(n1).Chebyshev()
+			}
 			return n2.Sub(n1).Manhattan()
 		},
 		Cost: func(n1, n2 util.Vec2D) int {
 			return 1
 		},
-		TieBreak: tieBreak,
+		TieBreak: util.Vec2D.ReadingLess,
 	}
-	result, err := AStarSearch(&search)
-	return result, err
 }
 
 type Battle struct {
+	Rules        Rules
 	Units        []*Unit
-	Map          [][]byte
+	Map          util.Grid[byte]
 	MapSize      util.Vec2D
 	WallCount    int
 	NonWallCount int
+	// Number of rounds started
+	Round int
+	// Debug logs each round, trace logs each unit's turn
+	Log *util.Logger
+	// Called for everything that happens in the battle, if set
+	OnEvent func(e Event)
+	// End combat as soon as an elf dies, instead of fighting to the end (needs Rules.Elves)
+	AbortOnElfDeath bool
+	// Distance field for each faction, see DistanceField
+	fields []*DistanceField
+	// Squares that have changed between floor and unit, in order, so distance fields can catch up
+	changes []util.Vec2D
+}
+
+// Create a battle with the puzzle's rules
+func NewBattle(input []string) Battle {
+	return NewBattleWithRules(input, DefaultRules())
 }
 
-func NewBattle(input []string) Battle {
-	b := Battle{}
+func NewBattleWithRules(input []string, rules Rules) Battle {
+	b := Battle{Rules: rules}
 	b.Units = make([]*Unit, 0)
-	b.MapSize = util.Vec2D{len(input[0]), len(input)}
 	b.WallCount = 0
-	b.Map = util.NewByteGrid(b.MapSize.X, b.MapSize.Y)
-
-	for y, line := range input {
-		for x := range line {
-			switch line[x] {
-			case InputWall:
-				b.Map[x][y] = MapWall
-				b.WallCount += 1
-			case InputFloor:
-				b.Map[x][y] = MapFloor
-			case InputElf:
-				b.CreateUnit(false, x, y)
-			case InputGoblin:
-				b.CreateUnit(true, x, y)
+	b.Map = util.ParseGrid(input, func(p util.Vec2D, c byte) byte {
+		switch c {
+		case InputWall, InputOutside:
+			// Nothing can get outside the map, so it might as well be wall
+			b.WallCount += 1
+			return MapWall
+		case InputFloor:
+			return MapFloor
+		default:
+			if faction, ok := b.Rules.factionByGlyph(c); ok {
+				return b.CreateUnit(faction, p).Id
 			}
+			panic(fmt.Sprintf("invalid map square at %v: %q", p, c))
 		}
-	}
+	})
+	b.MapSize = util.Vec2D{b.Map.Width(), b.Map.Height()}
 
 	b.NonWallCount = b.MapSize.Area() - b.WallCount
 
 	return b
 }
 
+/*
+Clone creates a copy of the battle that shares nothing with the original, so
+that both can carry on fighting independently, e.g. in different goroutines.
+OnEvent is not copied.
+*/
+func (b *Battle) Clone() *Battle {
+	c := *b
+	c.Rules.Factions = slices.Clone(b.Rules.Factions)
+	c.Map = b.Map.Copy()
+	c.Units = make([]*Unit, len(b.Units))
+	for i, u := range b.Units {
+		cu := *u
+		cu.Battle = &c
+		c.Units[i] = &cu
+	}
+	c.OnEvent = nil
+	c.fields = nil
+	c.changes = nil
+	return &c
+}
+
 func (b *Battle) CreateOverlay() BitMap {
-	return BitMap(util.NewBoolGrid(b.MapSize.X, b.MapSize.Y))
+	return NewBitMap(b.MapSize)
 }
 
 func (b *Battle) CreateOverlapFromPoints(points []util.Vec2D) BitMap {
@@ -206,49 +268,78 @@ func (b *Battle) String() string {
 }
 
 func (b *Battle) MapView(overlay BitMap, overlayChar byte, withUnits bool) string {
-	sb := strings.Builder{}
-	sb.Grow(b.MapSize.X*b.MapSize.Y + len(b.Units)*12)
+	r := b.Renderer()
+	r.Layers = append(r.Layers, util.MaskLayer(&overlay.Grid, overlayChar, util.AnsiYellow))
+	if !withUnits {
+		r.RowSuffix = nil
+	}
+	return r.Render(&b.Map)
+}
 
-	for y := 0; y < b.MapSize.Y; y++ {
-		units := make([]byte, 0)
-		for x := 0; x < b.MapSize.X; x++ {
-			p := util.Vec2D{x, y}
-			if overlay.Get(p) {
-				sb.WriteByte(overlayChar)
-			} else {
-				switch i := b.Map[x][y]; i {
-				case MapWall:
-					sb.WriteByte(InputWall)
-				case MapFloor:
-					sb.WriteByte(InputFloor)
-				default:
-					units = append(units, i)
-					switch b.Units[i].IsGoblin {
-					case false:
-						sb.WriteByte(InputElf)
-					case true:
-						sb.WriteByte(InputGoblin)
-					}
-				}
+/*
+Renderer creates a renderer for the battle map, drawing units with their
+input characters and listing the units on each row after the row.
+*/
+func (b *Battle) Renderer() util.GridRenderer[byte] {
+	return util.GridRenderer[byte]{
+		Glyph: func(p util.Vec2D, i byte) byte {
+			switch i {
+			case MapWall:
+				return InputWall
+			case MapFloor:
+				return InputFloor
+			default:
+				return b.Units[i].Glyph()
 			}
-		}
-		if withUnits {
+		},
+		Colour: func(p util.Vec2D, i byte) string {
+			switch i {
+			case MapWall, MapFloor:
+				return ""
+			default:
+				return colours[b.Rules.Factions[b.Units[i].Faction].Colour]
+			}
+		},
+		RowSuffix: func(y int) string {
+			sb := strings.Builder{}
 			sb.WriteString("   ")
-			for _, i := range units {
-				sb.WriteByte(' ')
-				sb.WriteString(b.Units[i].String())
+			for x := 0; x < b.MapSize.X; x++ {
+				if i := b.Map.Get(util.Vec2D{x, y}); i != MapWall && i != MapFloor {
+					sb.WriteByte(' ')
+					sb.WriteString(b.Units[i].String())
+				}
 			}
-		}
-		sb.WriteByte('\n')
+			return sb.String()
+		},
 	}
+}
 
-	return sb.String()
+/*
+PuzzleView draws the map like the puzzle text does, with the hit points of the
+units on each row listed after the row, e.g. "#G.E#   G(200), E(131)".
+*/
+func (b *Battle) PuzzleView() string {
+	r := b.Renderer()
+	r.RowSuffix = func(y int) string {
+		units := make([]string, 0)
+		for x := 0; x < b.MapSize.X; x++ {
+			if i := b.Map.Get(util.Vec2D{x, y}); i != MapWall && i != MapFloor {
+				u := b.Units[i]
+				units = append(units, fmt.Sprintf("%c(%d)", r.Glyph(u.Position, i), u.HitPoints))
+			}
+		}
+		if len(units) == 0 {
+			return ""
+		}
+		return "   " + strings.Join(units, ", ")
+	}
+	return r.Render(&b.Map)
 }
 
 func (b *Battle) SortUnits() {
 	// Sort by the "tie break" criteria
 	sort.Slice(b.Units, func(i, j int) bool {
-		return tieBreak(b.Units[i].Position, b.Units[j].Position)
+		return b.Units[i].Position.ReadingLess(b.Units[j].Position)
 	})
 	// Update all the unit IDs
 	for i, u := range b.Units {
@@ -260,53 +351,54 @@ func (b *Battle) SortUnits() {
 }
 
 func (b *Battle) At(p util.Vec2D) *byte {
-	return &b.Map[p.X][p.Y]
+	return b.Map.At(p)
 }
 
 func (b *Battle) ValidPosition(p util.Vec2D) bool {
-	switch {
-	case p.X < 0, p.X >= b.MapSize.X, p.Y < 0, p.Y >= b.MapSize.Y:
-		return false
-	default:
-		return true
-	}
+	return b.Map.Valid(p)
 }
 
 func (b *Battle) Adjacent(p util.Vec2D, floorOnly bool) []util.Vec2D {
-	// Possible adjacent squares, in "reading order"
-	candidates := []util.Vec2D{
-		p.Add(util.Vec2D{0, -1}),
-		p.Add(util.Vec2D{-1, 0}),
-		p.Add(util.Vec2D{1, 0}),
-		p.Add(util.Vec2D{0, 1}),
-	}
-	result := make([]util.Vec2D, 0, 4)
+	// Adjacent squares, in "reading order"
+	var candidates []util.Vec2D
+	if b.Rules.Diagonal {
+		candidates = b.Map.Neighbours8(p)
+	} else {
+		candidates = b.Map.Neighbours4(p)
+	}
+	if !floorOnly {
+		return candidates
+	}
+	result := candidates[:0]
 	for _, c := range candidates {
-		if b.ValidPosition(c) && (!floorOnly || *b.At(c) == MapFloor) {
+		if *b.At(c) == MapFloor {
 			result = append(result, c)
 		}
 	}
 	return result
 }
 
-func (b *Battle) CreateUnit(isGoblin bool, x, y int) {
+// Create a unit of faction at p, leaving the caller to put it on the map
+func (b *Battle) CreateUnit(faction int, p util.Vec2D) *Unit {
+	f := &b.Rules.Factions[faction]
 	u := Unit{
-		b,
-		byte(len(b.Units)),
-		util.Vec2D{x, y},
-		isGoblin,
-		200,
-		3,
-		nil,
-		util.Vec2D{-1, -1},
+		Battle:      b,
+		Id:          byte(len(b.Units)),
+		Serial:      len(b.Units),
+		Position:    p,
+		Faction:     faction,
+		HitPoints:   f.HitPoints,
+		AttackPower: f.AttackPower,
 	}
+	u.name = fmt.Sprintf("%s%d", f.Glyph, u.Serial)
 	b.Units = append(b.Units, &u)
-	b.Map[x][y] = u.Id
+	return &u
 }
 
 func (b *Battle) MoveUnit(u *Unit, p util.Vec2D) {
 	*b.At(p) = u.Id
 	*b.At(u.Position) = MapFloor
+	b.changes = append(b.changes, u.Position, p)
 	u.Position = p
 }
 
@@ -314,6 +406,7 @@ func (b *Battle) AttackUnit(u *Unit, damage int) {
 	u.HitPoints -= damage
 	if u.HitPoints <= 0 {
 		*b.At(u.Position) = MapFloor
+		b.changes = append(b.changes, u.Position)
 	}
 }
 
@@ -327,31 +420,24 @@ func (b *Battle) FindTargets(u *Unit) []*Unit {
 	return result
 }
 
+/*
+FindDestinations finds all the squares in range of any of targets which u
+could move to, i.e. empty squares adjacent to a target, plus u's own position
+if it's already adjacent to a target. The result is in "reading order".
+*/
 func (b *Battle) FindDestinations(u *Unit, targets []*Unit) []util.Vec2D {
-	reachable := u.Reachable()
-	resultSet := make(map[util.Vec2D]struct{})
+	resultSet := util.NewSet[util.Vec2D]()
 
-	// Find all the reachable locations in range of a target, de-duplicated
+	// Find all the locations in range of a target, de-duplicated
 	for _, t := range targets {
-		// Don't need floorOnly because non-floor squares are not in reachable set
 		for _, p := range b.Adjacent(t.Position, false) {
-			if reachable.Get(p) {
-				resultSet[p] = struct{}{}
+			if p == u.Position || *b.At(p) == MapFloor {
+				resultSet.Add(p)
 			}
 		}
 	}
 
-	// Convert to slice
-	result := make([]util.Vec2D, 0, len(resultSet))
-	for p := range resultSet {
-		result = append(result, p)
-	}
-
-	// Sort by distance
-	sort.Slice(result, func(i, j int) bool {
-		return (&result[i]).Sub(u.Position).Length() < (&result[j]).Sub(u.Position).Length()
-	})
-	return result
+	return resultSet.SortedFunc(util.Vec2D.ReadingLess)
 }
 
 func (b *Battle) RemainingHitPoints() int {
@@ -364,39 +450,56 @@ func (b *Battle) RemainingHitPoints() int {
 	return result
 }
 
+func (b *Battle) emit(e Event) {
+	if b.OnEvent != nil {
+		e.Round = b.Round
+		b.OnEvent(e)
+	}
+}
+
 func (b *Battle) NextRound() (combatEnded bool) {
 	b.SortUnits()
-	//fmt.Print("start of round:\n", b.String())
+	b.Round++
+	b.emit(Event{Type: EventRound})
+	if b.Log.Enabled(util.LevelDebug) {
+		b.Log.Debugf("start of round:\n%s", b.String())
+	}
 
 	for _, u := range b.Units {
 		if !u.IsAlive() {
 			// Dead units don't move!
 			continue
 		}
-		//fmt.Printf("new turn: %s\n", u.String())
-		//fmt.Print("reachable overlay:\n", b.MapView(u.Reachable(), '+', false))
+		log := b.Log
+		if log.Enabled(util.LevelTrace) {
+			log = log.With("unit", u.String())
+		}
+		log.Tracef("new turn")
 		// Find targets
 		targets := b.FindTargets(u)
 		if len(targets) == 0 {
 			// Combat ended, one side has no remaining units
-			//fmt.Println("  no targets, combat ended")
+			log.Tracef("no targets, combat ended")
+			b.emit(Event{Type: EventEnd, Unit: u.Name()})
 			return true
 		}
-		// Find path to nearest position in range of a target, and move towards it
-		destinations := b.FindDestinations(u, targets)
-		//fmt.Println("  destinations:", destinations)
-		//fmt.Print("destination overlay:\n", b.MapView(b.CreateOverlapFromPoints(destinations), '@', false))
-		path, err := u.FindPath(destinations)
-		if err != nil {
+		// Move towards the nearest position in range of a target
+		if log.Enabled(util.LevelTrace) {
+			destinations := b.FindDestinations(u, targets)
+			log.Tracef("destinations: %v\n%s", destinations, b.MapView(b.CreateOverlapFromPoints(destinations), '@', false))
+		}
+		step, ok := b.DistanceField(u.Faction).Move(u)
+		if !ok {
 			// Can't find any targets, so end turn
-			//fmt.Println("  no path found")
+			log.Tracef("no path found")
 			continue
 		}
-		//fmt.Println("  path found:", path)
-		if len(path) > 0 {
+		if step != u.Position {
 			// Not already in position to attack, so move 1 step
-			//fmt.Println("  moving from", u.Position, "to", path[0])
-			b.MoveUnit(u, path[0])
+			log.Tracef("moving from %v to %v", u.Position, step)
+			from := u.Position
+			b.emit(Event{Type: EventMove, Unit: u.Name(), From: &from, To: &step})
+			b.MoveUnit(u, step)
 		}
 		// Find best adjacent enemy
 		var target *Unit
@@ -414,104 +517,194 @@ func (b *Battle) NextRound() (combatEnded bool) {
 			case target == nil:
 				// First enemy found
 				target = newTarget
-			case newTarget.HitPoints < target.HitPoints:
-				// Weaker enemy than current target. Equal HP tie-break is
-				// already handled by Adjacent being in tie-break order (i.e.
-				// first target with a specific HP remains the target).
+			case b.Rules.Targeting.better(u.Position, newTarget, target):
+				// Better target according to the rules, e.g. a weaker enemy
 				target = newTarget
 			}
 		}
 		// If we have an enemy, attack it
 		if target != nil {
-			//fmt.Println("  attacking target:", target.String())
+			log.Tracef("attacking target %s", target.String())
 			b.AttackUnit(target, u.AttackPower)
+			b.emit(Event{Type: EventAttack, Unit: u.Name(), Target: target.Name(), Damage: u.AttackPower, HitPoints: target.HitPoints})
 			if !target.IsAlive() {
-				//fmt.Println("  killed target:", target.String())
+				log.Tracef("killed target %s", target.String())
+				b.emit(Event{Type: EventDeath, Unit: target.Name()})
+				if target.IsElf() && b.AbortOnElfDeath {
+					log.Tracef("elf died, combat aborted")
+					return true
+				}
 			}
 		}
 	}
 	return false
 }
 
+// Set the attack power of every elf, panicking if the rules have no elves
 func (b *Battle) BuffElves(power int) {
+	b.Rules.mustElfFaction()
 	for _, u := range b.Units {
-		if !u.IsGoblin {
+		if u.IsElf() {
 			u.AttackPower = power
 		}
 	}
 }
 
+// Number of elves that have died, panicking if the rules have no elves
 func (b *Battle) CountDeadElves() int {
+	b.Rules.mustElfFaction()
 	count := 0
 	for _, u := range b.Units {
-		if !u.IsGoblin && !u.IsAlive() {
+		if u.IsElf() && !u.IsAlive() {
 			count++
 		}
 	}
 	return count
 }
 
-func part1impl(logger *log.Logger, input []string, maxRounds int, interactive bool) (rounds, remainingHP int) {
+func part1impl(logger *util.Logger, input []string, maxRounds int, interactive bool) (rounds, remainingHP int) {
 	battle := NewBattle(input)
-	//logger.Printf("input:\n%s\n", battle.MapView(battle.CreateOverlay(), '+', false))
+	battle.Log = logger.Sub("battle")
+	if logger.Enabled(util.LevelDebug) {
+		logger.Debugf("input:\n%s", battle.MapView(battle.CreateOverlay(), '+', false))
+	}
+	// Record the battle to step through it afterwards
+	recording := NewRecording(input, battle.Rules)
+	if interactive {
+		battle.OnEvent = recording.Record
+	}
 	combatEnded := false
-	reader := bufio.NewReader(os.Stdin)
 	var i int
 	for i = 0; !combatEnded && i < maxRounds; i++ {
-		if interactive {
-			fmt.Print("Hit enter to continue...")
-			reader.ReadString('\n')
-		}
 		combatEnded = battle.NextRound()
-		//logger.Printf("end of round %d:\n%s\n", i+1, battle.MapView(battle.CreateOverlay(), '+', false))
+		if logger.Enabled(util.LevelDebug) {
+			logger.Debugf("end of round %d:\n%s", i+1, battle.MapView(battle.CreateOverlay(), '+', false))
+		}
+	}
+	if interactive {
+		util.Check(Play(os.Stdin, os.Stdout, recording))
 	}
 	return i - 1, battle.RemainingHitPoints()
 }
 
-func part1(logger *log.Logger, filename string, maxRounds int, interactive bool) string {
+func part1(logger *util.Logger, filename string, maxRounds int, interactive bool) string {
 	input, _ := util.ReadLinesFromFile(filename)
 	rounds, remainingHP := part1impl(logger, input, maxRounds, interactive)
 	return fmt.Sprintf("%dx%d = %d", rounds, remainingHP, rounds*remainingHP)
 }
 
-func part2(logger *log.Logger, filename string) string {
-	input, _ := util.ReadLinesFromFile(filename)
+// Result of fighting a battle with the elves' attack power increased
+type powerOutcome struct {
+	power       int
+	elvesWon    bool
+	rounds      int
+	remainingHP int
+}
 
-	power := 4
+// Fight a copy of initial with the elves at power, giving up as soon as an elf dies
+func fightWithPower(initial *Battle, power int) powerOutcome {
+	b := initial.Clone()
+	b.Log = initial.Log.With("power", power)
+	b.BuffElves(power)
+	b.AbortOnElfDeath = true
 	rounds := 0
-	remainingHP := 0
-increasePower:
-	for ; ; power++ {
-		b := NewBattle(input)
-		b.BuffElves(power)
-		combatEnded := false
-		for rounds = 0; !combatEnded; rounds++ {
-			combatEnded = b.NextRound()
-			if b.CountDeadElves() > 0 {
-				continue increasePower
+	for combatEnded := false; !combatEnded; rounds++ {
+		combatEnded = b.NextRound()
+	}
+	if b.CountDeadElves() > 0 {
+		b.Log.Debugf("elf died in round %d", rounds)
+		return powerOutcome{power: power}
+	}
+	return powerOutcome{power, true, rounds - 1, b.RemainingHitPoints()}
+}
+
+// Fight a copy of initial for each of powers at the same time
+func fightWithPowers(initial *Battle, powers []int) []powerOutcome {
+	result := make([]powerOutcome, len(powers))
+	wg := sync.WaitGroup{}
+	for i, power := range powers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result[i] = fightWithPower(initial, power)
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+/*
+findElfPower finds the lowest attack power above lo for the elves to win
+without losses, fighting up to workers battles at once.
+
+Relies on more power never being worse for the elves, so that every power is
+either a loss (at or below lo) or a win (at or above the best so far). While
+there are no wins, powers are tried galloping upwards from lo with a step that
+doubles every time, i.e. lo+1, lo+2, lo+4, ..., then once there is a win the
+gap between the highest loss and the win is split evenly between the workers
+until they meet.
+*/
+func findElfPower(t *util.Timer, initial *Battle, lo int, workers int) powerOutcome {
+	best := powerOutcome{}
+	step := 1
+	for !best.elvesWon || best.power > lo+1 {
+		powers := make([]int, 0, workers)
+		if !best.elvesWon {
+			for i := 0; i < workers; i++ {
+				powers = append(powers, lo+step)
+				step *= 2
+			}
+		} else {
+			n := util.MinInt(workers, best.power-lo-1)
+			for i := 1; i <= n; i++ {
+				powers = append(powers, lo+i*(best.power-lo)/(n+1))
 			}
 		}
-		if b.CountDeadElves() == 0 {
-			remainingHP = b.RemainingHitPoints()
-			rounds -= 1
-			break
+		t.Log.Debugf("trying powers %v", powers)
+		var outcomes []powerOutcome
+		t.Time("battles", func() {
+			outcomes = fightWithPowers(initial, powers)
+		})
+		for _, o := range outcomes {
+			switch {
+			case !o.elvesWon:
+				lo = util.MaxInt(lo, o.power)
+			case !best.elvesWon || o.power < best.power:
+				best = o
+			}
 		}
 	}
+	return best
+}
 
+func part2impl(logger *util.Logger, input []string) (power, rounds, remainingHP int) {
+	t := util.NewTimer(logger, "")
+	defer t.LogReport()
+	b := NewBattle(input)
+	b.Log = logger.Sub("battle")
+	// Part 1 is the elves losing with their default power
+	lo := b.Rules.Factions[b.Rules.mustElfFaction()].AttackPower
+	best := findElfPower(&t, &b, lo, runtime.NumCPU())
+	return best.power, best.rounds, best.remainingHP
+}
+
+func part2(logger *util.Logger, filename string) string {
+	input, _ := util.ReadLinesFromFile(filename)
+	power, rounds, remainingHP := part2impl(logger, input)
 	return fmt.Sprintf("power %d, %dx%d = %d", power, rounds, remainingHP, rounds*remainingHP)
 }
 
 func init() {
-	//util.RegisterSolution("day15test1", func(logger *log.Logger) string {
+	//util.RegisterSolution("day15test1", func(logger *util.Logger) string {
 	//	return part1(logger, "day15/input_test1.txt", 3, false)
 	//})
-	//util.RegisterSolution("day15test2", func(logger *log.Logger) string {
+	//util.RegisterSolution("day15test2", func(logger *util.Logger) string {
 	//	return part1(logger, "day15/input_test2.txt", 50, false)
 	//})
-	util.RegisterSolution("day15part1", func(logger *log.Logger) string {
+	util.RegisterSolution("day15part1", func(logger *util.Logger) string {
 		return part1(logger, "day15/input.txt", math.MaxInt32, false)
 	})
-	util.RegisterSolution("day15part2", func(logger *log.Logger) string {
+	util.RegisterSolution("day15part2", func(logger *util.Logger) string {
 		return part2(logger, "day15/input.txt")
 	})
 }
@@ -1,15 +1,16 @@
 package day15
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"os"
+	"strings"
 	"testing"
 )
 
 func TestPart1Impl(t *testing.T) {
-	logger := log.New(os.Stdout, "", 0)
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
 	tables := []struct{
 		input []string
 		rounds int
@@ -154,6 +155,24 @@ func TestEdgeCase(t *testing.T) {
 	}
 }
 
+func TestRaggedMap(t *testing.T) {
+	// Short lines and trailing spaces are outside the map, so the map is as wide as the longest line
+	input := []string{
+		"#####",
+		"#E.G# ",
+		"####",
+	}
+	b := NewBattle(input)
+	if b.MapSize != (util.Vec2D{6, 3}) || b.MapSize != b.Map.Max().Add(util.Vec2D{1, 1}) {
+		t.Errorf("expected 6x3 map, got %v", b.MapSize)
+	}
+	if *b.At(util.Vec2D{4, 2}) != MapWall || *b.At(util.Vec2D{5, 1}) != MapWall || b.NonWallCount != 3 {
+		t.Errorf("expected everything outside the map to be wall:\n%s", b.String())
+	}
+	for !b.NextRound() {
+	}
+}
+
 func TestEqualPathReadingOrder(t *testing.T) {
 	input := []string{
 		"###########",
@@ -172,3 +191,402 @@ func TestEqualPathReadingOrder(t *testing.T) {
 		t.Errorf("expected %v, got %v", expected, b.Units[0].Position)
 	}
 }
+
+func TestRecordingReplay(t *testing.T) {
+	input := []string{
+		"#######",
+		"#.G...#",
+		"#...EG#",
+		"#.#.#G#",
+		"#..G#E#",
+		"#.....#",
+		"#######",
+	}
+	b := NewBattle(input)
+	recording := NewRecording(input, b.Rules)
+	b.OnEvent = recording.Record
+	for !b.NextRound() {
+	}
+
+	buf := bytes.Buffer{}
+	util.Check(recording.WriteJSONLines(&buf))
+	loaded, err := ReadRecording(&buf, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Rounds() != 48 {
+		t.Errorf("expected 48 rounds, got %d", loaded.Rounds())
+	}
+
+	p := NewPlayer(loaded)
+	p.Seek(loaded.Rounds())
+	p.Prev()
+	p.Next()
+	if p.Battle.RemainingHitPoints() != b.RemainingHitPoints() || p.Battle.PuzzleView() != b.PuzzleView() {
+		t.Errorf("expected:\n%s\ngot:\n%s", b.PuzzleView(), p.Battle.PuzzleView())
+	}
+}
+
+func TestReadInvalidRecording(t *testing.T) {
+	start := `{"type": "start", "input": ["#####", "#E.G#", "#####"]}` + "\n"
+	invalid := []string{
+		`{"type": "start", "input": ["#E?G#"]}`,
+		`{"type": "move", "round": 1, "unit": "X9", "from": {"X": 1, "Y": 1}, "to": {"X": 2, "Y": 1}}`,
+		`{"type": "move", "round": 1, "unit": "E0", "from": {"X": 1, "Y": 1}}`,
+		`{"type": "move", "round": 1, "unit": "E0", "from": {"X": 1, "Y": 1}, "to": {"X": 1, "Y": 0}}`,
+		`{"type": "move", "round": 1, "unit": "E0", "from": {"X": 1, "Y": 1}, "to": {"X": 9, "Y": 9}}`,
+		`{"type": "attack", "round": 1, "unit": "E0", "target": "G2", "damage": 3, "hp": 197}`,
+		`{"type": "death", "round": 1, "unit": "G2"}`,
+		`{"type": "death", "round": 1, "unit": "G1"}`,
+		`{"type": "explode", "round": 1}`,
+	}
+	for _, line := range invalid {
+		if _, err := ReadRecording(strings.NewReader(start+line+"\n"), "test"); err == nil {
+			t.Errorf("expected error for %s", line)
+		} else if !strings.HasPrefix(err.Error(), "test:") {
+			t.Errorf("expected error with line number, got %v", err)
+		}
+	}
+	valid := `{"type": "move", "round": 1, "unit": "E0", "from": {"X": 1, "Y": 1}, "to": {"X": 2, "Y": 1}}`
+	if _, err := ReadRecording(strings.NewReader(start+valid+"\n"), "test"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestAnalytics(t *testing.T) {
+	input := []string{
+		"#######",
+		"#.G...#",
+		"#...EG#",
+		"#.#.#G#",
+		"#..G#E#",
+		"#.....#",
+		"#######",
+	}
+	b := NewBattle(input)
+	recording := NewRecording(input, b.Rules)
+	b.OnEvent = recording.Record
+	a := NewAnalytics(&b)
+	a.Attach(&b)
+	for !b.NextRound() {
+	}
+
+	dealt, taken, kills, deaths := 0, 0, 0, 0
+	for _, s := range a.Units {
+		dealt += s.DamageDealt
+		taken += s.DamageTaken
+		kills += s.Kills
+		if s.KilledBy != "" {
+			deaths++
+		}
+	}
+	if dealt != taken || kills != 2 || deaths != 2 {
+		t.Errorf("expected damage to balance and 2 kills, got %d dealt, %d taken, %d kills, %d deaths", dealt, taken, kills, deaths)
+	}
+	last := a.Rounds[len(a.Rounds)-1]
+	if !a.Ended || a.Round != 48 || len(a.Rounds) != 48 || last.FactionHP[0] != 0 || last.FactionHP[1] != 590 {
+		t.Errorf("unexpected rounds %v", a.Rounds)
+	}
+	if summary := a.Summary(); !strings.HasPrefix(summary, "combat ended in round 48, goblin won, outcome 47x590 = 27730\n") {
+		t.Errorf("unexpected summary:\n%s", summary)
+	}
+
+	// Same statistics from the recording
+	replayed := AnalyseRecording(recording)
+	expected, actual := strings.Builder{}, strings.Builder{}
+	util.Check(a.WriteUnitsCSV(&expected))
+	util.Check(replayed.WriteUnitsCSV(&actual))
+	if expected.String() != actual.String() {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected.String(), actual.String())
+	}
+}
+
+func TestDistanceFieldMatchesFindMove(t *testing.T) {
+	input, err := util.ReadLinesFromFile("input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	factions, err := util.ReadLinesFromFile("input_factions.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagonal, err := LoadRules("rules_example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	battles := []Battle{NewBattle(input), NewBattleWithRules(factions, diagonal)}
+
+	for _, b := range battles {
+		for combatEnded := false; !combatEnded; combatEnded = b.NextRound() {
+			for _, u := range b.Units {
+				targets := b.FindTargets(u)
+				if !u.IsAlive() || len(targets) == 0 {
+					continue
+				}
+				expected, expectedOk := u.FindMove(b.FindDestinations(u, targets))
+				actual, actualOk := b.DistanceField(u.Faction).Move(u)
+				if actual != expected || actualOk != expectedOk {
+					t.Fatalf("round %d, %s: expected %v %v, got %v %v\n%s", b.Round, u, expected, expectedOk, actual, actualOk, b.String())
+				}
+			}
+		}
+	}
+}
+
+func TestDistanceFieldIncremental(t *testing.T) {
+	input, err := util.ReadLinesFromFile("input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	factions, err := util.ReadLinesFromFile("input_factions.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagonal, err := LoadRules("rules_example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Incremental updates give the same distances as searching from scratch
+	check := func(b *Battle, when string) {
+		for faction := range b.Rules.Factions {
+			actual, expected := b.DistanceField(faction), b.newDistanceField(faction)
+			expected.Distance.Traverse(func(p util.Vec2D, d *int) {
+				if a := actual.Distance.Get(p); a != *d || (a != Unreachable && actual.Nearest.Get(p) != expected.Nearest.Get(p)) {
+					t.Fatalf("%s, faction %d at %v: expected %d to %v, got %d to %v\n%s", when, faction, p,
+						*d, expected.Nearest.Get(p), a, actual.Nearest.Get(p), b.String())
+				}
+			})
+		}
+	}
+	for _, b := range []Battle{NewBattle(input), NewBattleWithRules(factions, diagonal)} {
+		// After every move and death
+		each := b.Clone()
+		each.OnEvent = func(e Event) {
+			if e.Type == EventMove || e.Type == EventDeath {
+				check(each, fmt.Sprintf("round %d, %s %s", each.Round, e.Type, e.Unit))
+			}
+		}
+		for !each.NextRound() {
+		}
+		// After a round's worth of changes at once, for the factions that didn't move last
+		for !b.NextRound() {
+			check(&b, fmt.Sprintf("round %d", b.Round))
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules, err := LoadRules("rules_example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules.Factions) != 3 || !rules.Diagonal || rules.Targeting != TargetHighestAttack {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+
+	// Everyone is adjacent (including diagonally), and targets the highest attack power
+	b := NewBattleWithRules([]string{
+		"#####",
+		"#E..#",
+		"#TG.#",
+		"#####",
+	}, rules)
+	b.NextRound()
+	hp := make(map[byte]int)
+	for _, u := range b.Units {
+		hp[u.Glyph()] = u.HitPoints
+	}
+	if hp['E'] != 200-5 || hp['T'] != 400-3-3 || hp['G'] != 200 {
+		t.Errorf("unexpected round:\n%s", b.PuzzleView())
+	}
+
+	// Factions in a file don't pick up anything from the default factions
+	dir := t.TempDir()
+	files := []struct {
+		json  string
+		valid bool
+	}{
+		{`{"factions": [{"glyph": "X", "hp": 10, "attack": 1}, {"glyph": "Y", "hp": 20, "attack": 2}]}`, true},
+		{`{"factions": [{"glyph": "X", "hp": 10, "attack": 1}, {"glyph": "Y", "hp": 20}]}`, false},
+		{`{"factions": [{"glyph": "X", "attack": 1}, {"glyph": "Y", "hp": 20, "attack": 2}]}`, false},
+		{`{"diagonal": true}`, true},
+		{`{"factions": [{"glyph": "X", "hp": 10, "attack": 1}, {"glyph": "Y", "hp": 20, "attack": 2}], "elves": "Z"}`, false},
+	}
+	for i, file := range files {
+		filename := fmt.Sprintf("%s/rules%d.json", dir, i)
+		util.Check(os.WriteFile(filename, []byte(file.json), 0644))
+		if _, err := LoadRules(filename); (err == nil) != file.valid {
+			t.Errorf("%s: expected valid = %v, got %v", file.json, file.valid, err)
+		}
+	}
+	rules, err = LoadRules(dir + "/rules0.json")
+	util.Check(err)
+	expected := Faction{Name: "X", Glyph: "X", HitPoints: 10, AttackPower: 1}
+	if rules.Factions[0] != expected || rules.Targeting != TargetLowestHP {
+		t.Errorf("expected %+v, got %+v", expected, rules)
+	}
+	rules, err = LoadRules(dir + "/rules3.json")
+	util.Check(err)
+	if !rules.Diagonal || len(rules.Factions) != 2 || rules.Factions[1] != DefaultRules().Factions[1] || rules.Elves != "elf" {
+		t.Errorf("expected default factions, got %+v", rules)
+	}
+
+	// Elves are whichever faction the rules name, whatever their glyph
+	reglyphed := DefaultRules()
+	reglyphed.Factions[0].Glyph = "X"
+	b = NewBattleWithRules([]string{"#XG#"}, reglyphed)
+	b.BuffElves(10)
+	if !b.Units[0].IsElf() || b.Units[1].IsElf() || b.Units[0].AttackPower != 10 {
+		t.Errorf("expected X to be an elf, got %v", b.Units)
+	}
+	renamed := DefaultRules()
+	renamed.Factions[0].Name = "pixie"
+	if err := renamed.Validate(); err == nil {
+		t.Errorf("expected error for missing elf faction")
+	}
+	renamed.Elves = ""
+	b = NewBattleWithRules([]string{"#EG#"}, renamed)
+	if b.Units[0].IsElf() || !panics(func() { b.BuffElves(10) }) || !panics(func() { b.CountDeadElves() }) {
+		t.Errorf("expected elf features to fail without an elf faction")
+	}
+
+	invalid := []func(r *Rules){
+		func(r *Rules) { r.Factions[1].Glyph = "E" },
+		func(r *Rules) { r.Factions[1].Glyph = "" },
+		func(r *Rules) { r.Factions[1].AttackPower = 0 },
+		func(r *Rules) { r.Factions[1].AttackPower = -3 },
+		func(r *Rules) { r.Factions = r.Factions[:1] },
+	}
+	for i, change := range invalid {
+		rules := DefaultRules()
+		change(&rules)
+		if err := rules.Validate(); err == nil {
+			t.Errorf("%d: expected error for %+v", i, rules)
+		}
+		// Recordings are checked too, instead of failing when played back
+		recording := NewRecording([]string{"#EG#"}, rules)
+		buf := bytes.Buffer{}
+		util.Check(recording.WriteJSONLines(&buf))
+		if _, err := ReadRecording(&buf, "test"); err == nil {
+			t.Errorf("%d: expected error reading recording with %+v", i, rules)
+		}
+	}
+}
+
+func TestPart2Impl(t *testing.T) {
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
+	tables := []struct {
+		input       []string
+		power       int
+		rounds      int
+		remainingHP int
+	}{
+		{
+			[]string{
+				"#######",
+				"#.G...#",
+				"#...EG#",
+				"#.#.#G#",
+				"#..G#E#",
+				"#.....#",
+				"#######",
+			},
+			15, 29, 172,
+		},
+		{
+			[]string{
+				"#######",
+				"#E..EG#",
+				"#.#G.E#",
+				"#E.##E#",
+				"#G..#.#",
+				"#..E#.#",
+				"#######",
+			},
+			4, 33, 948,
+		},
+		{
+			[]string{
+				"#######",
+				"#E.G#.#",
+				"#.#G..#",
+				"#G.#.G#",
+				"#G..#.#",
+				"#...E.#",
+				"#######",
+			},
+			15, 37, 94,
+		},
+		{
+			[]string{
+				"#######",
+				"#.E...#",
+				"#.#..G#",
+				"#.###.#",
+				"#E#G#G#",
+				"#...#G#",
+				"#######",
+			},
+			12, 39, 166,
+		},
+		{
+			[]string{
+				"#########",
+				"#G......#",
+				"#.E.#...#",
+				"#..##..G#",
+				"#...##..#",
+				"#...#...#",
+				"#.G...G.#",
+				"#.....G.#",
+				"#########",
+			},
+			34, 30, 38,
+		},
+	}
+
+	for _, table := range tables {
+		power, rounds, remainingHP := part2impl(logger, table.input)
+		if power != table.power || rounds != table.rounds || remainingHP != table.remainingHP {
+			t.Errorf("expected power %d, %dx%d, got power %d, %dx%d", table.power, table.rounds, table.remainingHP, power, rounds, remainingHP)
+		}
+		// Same result however many battles are fought at once
+		for workers := 2; workers <= 5; workers++ {
+			timer := util.NewTimer(nil, "")
+			b := NewBattle(table.input)
+			best := findElfPower(&timer, &b, 3, workers)
+			if best.power != table.power || best.rounds != table.rounds || best.remainingHP != table.remainingHP {
+				t.Errorf("expected power %d with %d workers, got %+v", table.power, workers, best)
+			}
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	b := NewBattle([]string{
+		"#######",
+		"#.G...#",
+		"#...EG#",
+		"#######",
+	})
+	c := b.Clone()
+	for !c.NextRound() {
+	}
+	if b.Round != 0 || b.RemainingHitPoints() != 600 || b.Map.Get(util.Vec2D{2, 1}) == MapFloor {
+		t.Errorf("original battle changed by clone:\n%s", b.PuzzleView())
+	}
+	for _, u := range c.Units {
+		if u.Battle != c {
+			t.Errorf("unit %s belongs to the wrong battle", u)
+		}
+	}
+}
+
+func panics(f func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	f()
+	return false
+}
@@ -0,0 +1,252 @@
+package day15
+
+import (
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"math"
+)
+
+// Distance of squares in a DistanceField with no path to any destination
+const Unreachable = math.MaxInt32
+
+/*
+DistanceField holds, for every floor square, the distance to the nearest
+square in range of an enemy of a faction, and which square that is, using
+"reading order" to choose between squares at the same distance. This starts
+as the result of a single breadth-first search from all of the squares in
+range at once, which every unit of the faction can share to find its move.
+
+When units move or die, the field is updated incrementally, only searching
+again around the squares that changed: see update.
+*/
+type DistanceField struct {
+	Faction  int
+	Distance util.Grid[int]
+	Nearest  util.Grid[util.Vec2D]
+	// Number of Battle.changes the field is up to date with
+	applied int
+}
+
+/*
+DistanceField gets the distance field for faction, bringing it up to date with
+any units that have moved or died since it was last used.
+*/
+func (b *Battle) DistanceField(faction int) *DistanceField {
+	if b.fields == nil {
+		b.fields = make([]*DistanceField, len(b.Rules.Factions))
+	}
+	f := b.fields[faction]
+	if f == nil {
+		f = b.newDistanceField(faction)
+		b.fields[faction] = f
+	}
+	if f.applied < len(b.changes) {
+		f.update(b, b.changes[f.applied:])
+		f.applied = len(b.changes)
+	}
+	return f
+}
+
+// Create the distance field for faction with a full search of the current map
+func (b *Battle) newDistanceField(faction int) *DistanceField {
+	f := &DistanceField{
+		Faction:  faction,
+		Distance: util.NewGrid[int](b.MapSize.X, b.MapSize.Y),
+		Nearest:  util.NewGrid[util.Vec2D](b.MapSize.X, b.MapSize.Y),
+		applied:  len(b.changes),
+	}
+	f.Distance.Initialize(Unreachable)
+	frontier := make([]util.Vec2D, 0)
+	for _, t := range b.Units {
+		if !t.IsAlive() || t.Faction == f.Faction {
+			continue
+		}
+		for _, p := range b.Adjacent(t.Position, true) {
+			if f.Distance.Get(p) != 0 {
+				f.Distance.Set(p, 0)
+				f.Nearest.Set(p, p)
+				frontier = append(frontier, p)
+			}
+		}
+	}
+
+	// Search outwards one step at a time, so that every square at distance d
+	// has its nearest destination settled before any square at d+1 is expanded
+	for d := 1; len(frontier) > 0; d++ {
+		next := make([]util.Vec2D, 0)
+		for _, p := range frontier {
+			nearest := f.Nearest.Get(p)
+			for _, n := range b.Adjacent(p, true) {
+				switch f.Distance.Get(n) {
+				case Unreachable:
+					f.Distance.Set(n, d)
+					f.Nearest.Set(n, nearest)
+					next = append(next, n)
+				case d:
+					if nearest.ReadingLess(f.Nearest.Get(n)) {
+						f.Nearest.Set(n, nearest)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return f
+}
+
+// Is (d1, n1) a shorter distance than (d2, n2), or the same distance to a nearest square earlier in "reading order"?
+func closer(d1 int, n1 util.Vec2D, d2 int, n2 util.Vec2D) bool {
+	return d1 < d2 || (d1 == d2 && d1 != Unreachable && n1.ReadingLess(n2))
+}
+
+// Is the floor square p in range of an enemy of the faction?
+func (f *DistanceField) inRange(b *Battle, p util.Vec2D) bool {
+	for _, n := range b.Adjacent(p, false) {
+		if at := *b.At(n); int(at) < len(b.Units) && b.Units[at].Faction != f.Faction {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+supported checks that the distance at p still holds, i.e. p is floor and is
+either in range of an enemy itself, or next to a square one step closer to
+the same nearest square. Every square's distance is supported like this by a
+neighbour, all the way back to its nearest square, so a square can only lose
+its support by being next to a square that changed or to a square that lost
+its own support.
+*/
+func (f *DistanceField) supported(b *Battle, p util.Vec2D) bool {
+	if *b.At(p) != MapFloor {
+		return false
+	}
+	d, nearest := f.Distance.Get(p), f.Nearest.Get(p)
+	if d == 0 {
+		return nearest == p && f.inRange(b, p)
+	}
+	for _, n := range b.Adjacent(p, true) {
+		if f.Distance.Get(n) == d-1 && f.Nearest.Get(n) == nearest {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+update brings the field up to date after squares have changed between floor
+and unit, in two passes, both working through squares in order of distance:
+
+  - Squares near the changes that have lost their support are made
+    unreachable, along with any squares that depended on them
+  - Distances spread again from the edge of that region, and from the changed
+    squares, which might be newly in range of an enemy or open up shorter
+    paths, for as long as they make some square closer
+
+This gives the same result as searching the whole map again, but only
+visits squares whose distance or nearest square could have changed.
+*/
+func (f *DistanceField) update(b *Battle, changes []util.Vec2D) {
+	candidates := make([]util.Vec2D, 0, len(changes)*9)
+	for _, c := range changes {
+		candidates = append(candidates, c)
+		candidates = append(candidates, b.Adjacent(c, false)...)
+	}
+
+	// Squares to visit at each distance, as distances spread outwards
+	buckets := make(map[int][]util.Vec2D)
+	lo, hi := Unreachable, -1
+	push := func(p util.Vec2D, d int) {
+		buckets[d] = append(buckets[d], p)
+		lo, hi = util.MinInt(lo, d), util.MaxInt(hi, d)
+	}
+
+	// Remove distances that no longer hold. Supports are one step closer, so
+	// always checked (and removed if need be) before the squares they support.
+	for _, p := range candidates {
+		if d := f.Distance.Get(p); d != Unreachable {
+			push(p, d)
+		}
+	}
+	removed := make([]util.Vec2D, 0)
+	for d := lo; d <= hi; d++ {
+		for _, p := range buckets[d] {
+			if f.Distance.Get(p) != d || f.supported(b, p) {
+				continue
+			}
+			nearest := f.Nearest.Get(p)
+			f.Distance.Set(p, Unreachable)
+			removed = append(removed, p)
+			for _, n := range b.Adjacent(p, true) {
+				if f.Distance.Get(n) == d+1 && f.Nearest.Get(n) == nearest {
+					push(n, d+1)
+				}
+			}
+		}
+		delete(buckets, d)
+	}
+
+	// Spread distances again, starting from the best each square can get from
+	// its neighbours (or from being in range itself)
+	lo, hi = Unreachable, -1
+	for _, p := range append(removed, candidates...) {
+		if *b.At(p) != MapFloor {
+			continue
+		}
+		d, nearest := f.Distance.Get(p), f.Nearest.Get(p)
+		if f.inRange(b, p) {
+			d, nearest = 0, p
+		}
+		for _, n := range b.Adjacent(p, true) {
+			if nd := f.Distance.Get(n); nd != Unreachable && closer(nd+1, f.Nearest.Get(n), d, nearest) {
+				d, nearest = nd+1, f.Nearest.Get(n)
+			}
+		}
+		if closer(d, nearest, f.Distance.Get(p), f.Nearest.Get(p)) {
+			f.Distance.Set(p, d)
+			f.Nearest.Set(p, nearest)
+			push(p, d)
+		}
+	}
+	for d := lo; d <= hi; d++ {
+		for _, p := range buckets[d] {
+			if f.Distance.Get(p) != d {
+				// Found a shorter path since
+				continue
+			}
+			nearest := f.Nearest.Get(p)
+			for _, n := range b.Adjacent(p, true) {
+				if closer(d+1, nearest, f.Distance.Get(n), f.Nearest.Get(n)) {
+					f.Distance.Set(n, d+1)
+					f.Nearest.Set(n, nearest)
+					push(n, d+1)
+				}
+			}
+		}
+		delete(buckets, d)
+	}
+}
+
+/*
+Move finds where u should move to get closer to the nearest square in range of
+an enemy, with the same result as FindMove with the destinations from
+FindDestinations: the nearest square in range of u is the smallest (distance,
+nearest) of u's neighbours, and the first neighbour in "reading order" to have
+it is the first step of a shortest path there.
+*/
+func (f *DistanceField) Move(u *Unit) (util.Vec2D, bool) {
+	b := u.Battle
+	for _, p := range b.Adjacent(u.Position, false) {
+		if at := *b.At(p); int(at) < len(b.Units) && u.IsEnemy(b.Units[at]) {
+			// Already in range of an enemy
+			return u.Position, true
+		}
+	}
+	step, distance, nearest := u.Position, Unreachable, util.Vec2D{}
+	for _, n := range b.Adjacent(u.Position, true) {
+		d := f.Distance.Get(n)
+		if d < distance || (d == distance && d != Unreachable && f.Nearest.Get(n).ReadingLess(nearest)) {
+			step, distance, nearest = n, d, f.Nearest.Get(n)
+		}
+	}
+	return step, distance != Unreachable
+}
@@ -0,0 +1,312 @@
+package day15
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type EventType string
+
+const (
+	// The input map and rules, so a recording can be played back on its own
+	EventStart EventType = "start"
+	// Start of a round, before any unit has taken its turn
+	EventRound EventType = "round"
+	// Unit moved From -> To
+	EventMove EventType = "move"
+	// Unit attacked Target for Damage, leaving it with HitPoints
+	EventAttack EventType = "attack"
+	// Unit died
+	EventDeath EventType = "death"
+	// Unit found no targets, so combat ended part way through the round
+	EventEnd EventType = "end"
+)
+
+// Something that happened in a Battle, identifying units by Unit.Name
+type Event struct {
+	Type      EventType   `json:"type"`
+	Round     int         `json:"round"`
+	Unit      string      `json:"unit,omitempty"`
+	Target    string      `json:"target,omitempty"`
+	From      *util.Vec2D `json:"from,omitempty"`
+	To        *util.Vec2D `json:"to,omitempty"`
+	Damage    int         `json:"damage,omitempty"`
+	HitPoints int         `json:"hp,omitempty"`
+	Input     []string    `json:"input,omitempty"`
+	Rules     *Rules      `json:"rules,omitempty"`
+}
+
+// Everything that happened in a battle, from its starting map and rules
+type Recording struct {
+	Input  []string
+	Rules  Rules
+	Events []Event
+}
+
+func NewRecording(input []string, rules Rules) *Recording {
+	return &Recording{Input: input, Rules: rules, Events: make([]Event, 0)}
+}
+
+// Use as Battle.OnEvent
+func (r *Recording) Record(e Event) {
+	r.Events = append(r.Events, e)
+}
+
+// Number of rounds started, including the round where combat ended
+func (r *Recording) Rounds() int {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return r.Events[len(r.Events)-1].Round
+}
+
+// Write the recording as one JSON event per line, starting with the input map
+func (r *Recording) WriteJSONLines(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(Event{Type: EventStart, Input: r.Input, Rules: &r.Rules}); err != nil {
+		return err
+	}
+	for _, e := range r.Events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Read a recording written by WriteJSONLines, with name identifying it in errors.
+Every event is checked against the units in the starting map, so that the
+recording can be played back.
+*/
+func ReadRecording(r io.Reader, name string) (*Recording, error) {
+	var result *Recording
+	var start Battle
+	var units util.Set[string]
+	reader := util.NewLineReader(r, name)
+	for line := range reader.Lines() {
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		e := Event{}
+		if err := json.Unmarshal([]byte(line.Text), &e); err != nil {
+			return nil, line.Errorf("%v", err)
+		}
+		switch {
+		case e.Type == EventStart:
+			rules := DefaultRules()
+			if e.Rules != nil {
+				rules = *e.Rules
+			}
+			if err := rules.Validate(); err != nil {
+				return nil, line.Errorf("%v", err)
+			}
+			if err := rules.CheckMap(e.Input); err != nil {
+				return nil, line.Errorf("%v", err)
+			}
+			result = NewRecording(e.Input, rules)
+			start = NewBattleWithRules(e.Input, rules)
+			units = util.NewSet[string]()
+			for _, u := range start.Units {
+				units.Add(u.Name())
+			}
+		case result == nil:
+			return nil, line.Errorf("expected %q event first, got %q", EventStart, e.Type)
+		default:
+			last := Event{}
+			if len(result.Events) > 0 {
+				last = result.Events[len(result.Events)-1]
+			}
+			if err := checkEvent(e, last, &start, units); err != nil {
+				return nil, line.Errorf("%v", err)
+			}
+			result.Record(e)
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%s: no %q event", name, EventStart)
+	}
+	return result, nil
+}
+
+/*
+Check that e, following last, refers to units in the starting map b, and has
+everything needed to play it back or analyse it.
+*/
+func checkEvent(e Event, last Event, b *Battle, units util.Set[string]) error {
+	known := func(names ...string) error {
+		for _, name := range names {
+			if !units.Contains(name) {
+				return fmt.Errorf("unknown unit %q in %q event", name, e.Type)
+			}
+		}
+		return nil
+	}
+	switch e.Type {
+	case EventRound:
+		return nil
+	case EventMove:
+		if err := known(e.Unit); err != nil {
+			return err
+		}
+		if e.From == nil || e.To == nil {
+			return fmt.Errorf("%q event for %q needs from and to", e.Type, e.Unit)
+		}
+		if !b.ValidPosition(*e.To) || *b.At(*e.To) == MapWall {
+			return fmt.Errorf("%q can't move into the wall at %d,%d", e.Unit, e.To.X, e.To.Y)
+		}
+		return nil
+	case EventAttack:
+		return known(e.Unit, e.Target)
+	case EventDeath:
+		if err := known(e.Unit); err != nil {
+			return err
+		}
+		// The attack that killed the unit gets the credit
+		if last.Type != EventAttack || last.Target != e.Unit {
+			return fmt.Errorf("%q event for %q doesn't follow an attack on it", e.Type, e.Unit)
+		}
+		return nil
+	case EventEnd:
+		return known(e.Unit)
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+}
+
+/*
+Player reconstructs the state of a recorded battle at the end of any round, by
+re-applying the recorded events to the starting map.
+*/
+type Player struct {
+	Recording *Recording
+	Battle    Battle
+	// Number of rounds applied so far
+	round int
+	// Next event to apply
+	next  int
+	units map[string]*Unit
+}
+
+func NewPlayer(r *Recording) *Player {
+	p := &Player{Recording: r}
+	p.reset()
+	return p
+}
+
+func (p *Player) reset() {
+	p.Battle = NewBattleWithRules(p.Recording.Input, p.Recording.Rules)
+	p.round, p.next = 0, 0
+	p.units = make(map[string]*Unit)
+	for _, u := range p.Battle.Units {
+		p.units[u.Name()] = u
+	}
+}
+
+func (p *Player) unit(name string) *Unit {
+	u, ok := p.units[name]
+	if !ok {
+		// ReadRecording checks for this, so the recording must have been changed since
+		panic(fmt.Sprintf("unknown unit %q in recording", name))
+	}
+	return u
+}
+
+func (p *Player) apply(e Event) {
+	switch e.Type {
+	case EventRound:
+		p.Battle.SortUnits()
+		p.Battle.Round = e.Round
+	case EventMove:
+		p.Battle.MoveUnit(p.unit(e.Unit), *e.To)
+	case EventAttack:
+		p.Battle.AttackUnit(p.unit(e.Target), e.Damage)
+	}
+}
+
+// Number of rounds played so far
+func (p *Player) Round() int {
+	return p.round
+}
+
+// Go to the end of round, 0 being the starting map
+func (p *Player) Seek(round int) {
+	round = util.MaxInt(0, util.MinInt(round, p.Recording.Rounds()))
+	if round < p.round {
+		p.reset()
+	}
+	for ; p.next < len(p.Recording.Events); p.next++ {
+		e := p.Recording.Events[p.next]
+		if e.Type == EventRound && e.Round > round {
+			break
+		}
+		p.apply(e)
+	}
+	p.round = round
+}
+
+func (p *Player) Next() {
+	p.Seek(p.round + 1)
+}
+
+func (p *Player) Prev() {
+	p.Seek(p.round - 1)
+}
+
+// The map with units' hit points listed beside each row, like the puzzle text
+func (p *Player) View() string {
+	return fmt.Sprintf("After %d rounds:\n%s", p.round, p.Battle.PuzzleView())
+}
+
+/*
+Play steps through a recording, reading commands from r and drawing the map to
+w after each one: enter or "n" for the next round, "p" for the previous round,
+"g N" to go to round N, and "q" to stop.
+*/
+func Play(r io.Reader, w io.Writer, rec *Recording) error {
+	p := NewPlayer(rec)
+	reader := bufio.NewReader(r)
+	for {
+		fmt.Fprintf(w, "%s\nround %d of %d [n]ext, [p]revious, [g]o to N, [q]uit: ", p.View(), p.Round(), rec.Rounds())
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		fields := strings.Fields(line)
+		command := ""
+		if len(fields) > 0 {
+			command = fields[0]
+		}
+		switch command {
+		case "", "n":
+			p.Next()
+		case "p":
+			p.Prev()
+		case "g":
+			if len(fields) < 2 {
+				fmt.Fprintln(w, "which round?")
+				continue
+			}
+			round, err := strconv.Atoi(fields[1])
+			if err != nil {
+				fmt.Fprintln(w, err)
+				continue
+			}
+			p.Seek(round)
+		case "q":
+			return nil
+		default:
+			fmt.Fprintf(w, "unknown command %q\n", command)
+		}
+	}
+}
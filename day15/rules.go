@@ -0,0 +1,211 @@
+package day15
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"os"
+)
+
+// A side in the battle: every unit attacks units of every other faction
+type Faction struct {
+	Name string `json:"name"`
+	// Character for the faction's units in the map
+	Glyph       string `json:"glyph"`
+	HitPoints   int    `json:"hp"`
+	AttackPower int    `json:"attack"`
+	// One of the colour names in colours, for rendering
+	Colour string `json:"colour"`
+}
+
+var colours = map[string]string{
+	"":        "",
+	"red":     util.AnsiRed,
+	"green":   util.AnsiGreen,
+	"yellow":  util.AnsiYellow,
+	"blue":    util.AnsiBlue,
+	"magenta": util.AnsiMagenta,
+	"cyan":    util.AnsiCyan,
+}
+
+// How a unit chooses which adjacent enemy to attack
+type TargetStrategy string
+
+const (
+	// Fewest hit points (the puzzle's rule)
+	TargetLowestHP TargetStrategy = "lowest-hp"
+	// Closest by Manhattan distance, which only matters with diagonal movement
+	TargetNearest TargetStrategy = "nearest"
+	// Most attack power, i.e. the most dangerous
+	TargetHighestAttack TargetStrategy = "highest-attack"
+)
+
+// Is a a better target than b for a unit at p? Ties are broken by "reading order" of the candidates.
+func (s TargetStrategy) better(p util.Vec2D, a, b *Unit) bool {
+	switch s {
+	case TargetNearest:
+		if da, db := a.Position.Sub(p).Manhattan(), b.Position.Sub(p).Manhattan(); da != db {
+			return da < db
+		}
+	case TargetHighestAttack:
+		if a.AttackPower != b.AttackPower {
+			return a.AttackPower > b.AttackPower
+		}
+	default:
+		if a.HitPoints != b.HitPoints {
+			return a.HitPoints < b.HitPoints
+		}
+	}
+	return a.Position.ReadingLess(b.Position)
+}
+
+// Rules of combat for a Battle
+type Rules struct {
+	Factions []Faction `json:"factions"`
+	// Can units move and attack diagonally?
+	Diagonal  bool           `json:"diagonal"`
+	Targeting TargetStrategy `json:"targeting"`
+	// Name of the faction that counts as the elves for part 2, if any
+	Elves string `json:"elves,omitempty"`
+}
+
+// The rules from the puzzle: elves versus goblins, each with 200 HP and 3 attack power
+func DefaultRules() Rules {
+	return Rules{
+		Factions: []Faction{
+			{Name: "elf", Glyph: string(InputElf), HitPoints: 200, AttackPower: 3, Colour: "green"},
+			{Name: "goblin", Glyph: string(InputGoblin), HitPoints: 200, AttackPower: 3, Colour: "red"},
+		},
+		Diagonal:  false,
+		Targeting: TargetLowestHP,
+		Elves:     "elf",
+	}
+}
+
+/*
+Load rules from a JSON file, e.g.
+
+	{
+		"factions": [
+			{"name": "elf", "glyph": "E", "hp": 200, "attack": 3, "colour": "green"},
+			{"name": "goblin", "glyph": "G", "hp": 200, "attack": 3, "colour": "red"}
+		],
+		"diagonal": false,
+		"targeting": "lowest-hp",
+		"elves": "elf"
+	}
+
+Each faction needs a glyph, hp and attack. Its name defaults to its glyph, and
+its colour to none. If factions are left out altogether, they're the same as
+in DefaultRules, including which are the elves, and targeting defaults to
+lowest-hp. Otherwise there are only elves if "elves" names one of the factions.
+*/
+func LoadRules(filename string) (Rules, error) {
+	rules := Rules{}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return rules, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("%s: %v", filename, err)
+	}
+	if rules.Factions == nil {
+		rules.Factions = DefaultRules().Factions
+		if rules.Elves == "" {
+			rules.Elves = DefaultRules().Elves
+		}
+	}
+	for i := range rules.Factions {
+		if f := &rules.Factions[i]; f.Name == "" {
+			f.Name = f.Glyph
+		}
+	}
+	if rules.Targeting == "" {
+		rules.Targeting = TargetLowestHP
+	}
+	if err := rules.Validate(); err != nil {
+		return rules, fmt.Errorf("%s: %v", filename, err)
+	}
+	return rules, nil
+}
+
+func (r *Rules) Validate() error {
+	if len(r.Factions) < 2 {
+		return fmt.Errorf("need at least 2 factions, got %d", len(r.Factions))
+	}
+	glyphs := util.NewSet[byte]()
+	for _, f := range r.Factions {
+		switch {
+		case len(f.Glyph) != 1:
+			return fmt.Errorf("faction %q: glyph must be a single character, got %q", f.Name, f.Glyph)
+		case f.Glyph[0] == InputWall || f.Glyph[0] == InputFloor || f.Glyph[0] == InputOutside || glyphs.Contains(f.Glyph[0]):
+			return fmt.Errorf("faction %q: glyph %q is already in use", f.Name, f.Glyph)
+		case f.HitPoints <= 0:
+			return fmt.Errorf("faction %q: hp must be positive", f.Name)
+		case f.AttackPower < 1:
+			// Otherwise attacks heal, or factions that can't hurt each other fight forever
+			return fmt.Errorf("faction %q: attack must be at least 1", f.Name)
+		}
+		if _, ok := colours[f.Colour]; !ok {
+			return fmt.Errorf("faction %q: unknown colour %q", f.Name, f.Colour)
+		}
+		glyphs.Add(f.Glyph[0])
+	}
+	switch r.Targeting {
+	case TargetLowestHP, TargetNearest, TargetHighestAttack:
+	default:
+		return fmt.Errorf("unknown targeting %q", r.Targeting)
+	}
+	if _, ok := r.ElfFaction(); r.Elves != "" && !ok {
+		return fmt.Errorf("elves: no faction named %q", r.Elves)
+	}
+	return nil
+}
+
+// Index of the faction that counts as the elves, if there is one
+func (r *Rules) ElfFaction() (int, bool) {
+	for i, f := range r.Factions {
+		if r.Elves != "" && f.Name == r.Elves {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Index of the faction that counts as the elves, panicking if there isn't one
+func (r *Rules) mustElfFaction() int {
+	elves, ok := r.ElfFaction()
+	if !ok {
+		panic("no elf faction in the rules")
+	}
+	return elves
+}
+
+// Check that every square of input is wall, floor, or a unit of one of the factions
+func (r *Rules) CheckMap(input []string) error {
+	if len(input) == 0 {
+		return fmt.Errorf("empty map")
+	}
+	for y, line := range input {
+		for x := 0; x < len(line); x++ {
+			switch c := line[x]; c {
+			case InputWall, InputFloor, InputOutside:
+			default:
+				if _, ok := r.factionByGlyph(c); !ok {
+					return fmt.Errorf("invalid map square at %d,%d: %q", x, y, c)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Index of the faction whose units are drawn as c, if any
+func (r *Rules) factionByGlyph(c byte) (int, bool) {
+	for i, f := range r.Factions {
+		if f.Glyph[0] == c {
+			return i, true
+		}
+	}
+	return -1, false
+}
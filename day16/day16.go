@@ -1,11 +1,8 @@
 package day16
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
-	"strconv"
 	"strings"
 )
 
@@ -45,58 +42,48 @@ type TestCase struct {
 
 type Program []Op
 
-func parseRegister(input string) Registers {
-	result := Registers{}
-	result[0], _ = strconv.Atoi(input[9:10])
-	result[1], _ = strconv.Atoi(input[12:13])
-	result[2], _ = strconv.Atoi(input[15:16])
-	result[3], _ = strconv.Atoi(input[18:19])
-	return result
-}
-
-func parseOp(input string) Op {
-	result := Op{}
-	scanner := bufio.NewScanner(strings.NewReader(input))
-	scanner.Split(bufio.ScanWords)
-	for i := 0; i < 4; i++ {
-		scanner.Scan()
-		result[i], _ = strconv.Atoi(scanner.Text())
+// Read the 4 numbers from a line of registers or an instruction
+func parse4(line util.Line) [4]int {
+	result := [4]int{}
+	values := util.Ints(line.Text)
+	if len(values) != len(result) {
+		util.Check(line.Errorf("expected %d numbers, got %d", len(result), len(values)))
 	}
+	copy(result[:], values)
 	return result
 }
 
 func readInput(filename string) ([]TestCase, Program) {
-	lines, err := util.ReadLinesFromFile(filename)
+	sections, err := util.ReadSectionsFromFile(filename)
 	util.Check(err)
 
 	tests := make([]TestCase, 0)
 	program := make(Program, 0)
 
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		switch {
-		case line == "":
-			// Empty line, do nothing
-		case line[0] == 'B':
+	for _, section := range sections {
+		if strings.HasPrefix(section[0].Text, "Before") {
 			// Read a test case
+			if len(section) != 3 {
+				util.Check(section[0].Errorf("expected 3 lines in test case, got %d", len(section)))
+			}
 			test := TestCase{
-				In: parseRegister(line),
-				Op: parseOp(lines[i+1]),
-				Out: parseRegister(lines[i+2]),
+				In: Registers(parse4(section[0])),
+				Op: Op(parse4(section[1])),
+				Out: Registers(parse4(section[2])),
 			}
-			i += 2
 			tests = append(tests, test)
-		default:
+		} else {
 			// Read a bit of the program
-			op := parseOp(line)
-			program = append(program, op)
+			for _, line := range section {
+				program = append(program, Op(parse4(line)))
+			}
 		}
 	}
 
 	return tests, program
 }
 
-func part1(logger *log.Logger) string {
+func part1(logger *util.Logger) string {
 	tests, _ := readInput("day16/input.txt")
 
 	veryAmbiguousCount := 0
@@ -117,7 +104,7 @@ func part1(logger *log.Logger) string {
 	return fmt.Sprint(veryAmbiguousCount)
 }
 
-func part2(logger *log.Logger) string {
+func part2(logger *util.Logger) string {
 	tests, program := readInput("day16/input.txt")
 
 	opcodeToFunc := [16]OpFunc{}
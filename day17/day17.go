@@ -1,13 +1,8 @@
 package day17
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
-	"os"
-	"strconv"
-	"strings"
 )
 
 const (
@@ -22,38 +17,30 @@ type Line struct {
 }
 
 type Aquifer struct {
-	Min, Max      util.Vec2D
+	Bounds        util.Box2D
 	Width, Height int
-	Data          util.ByteGrid
+	Data          util.Grid[byte]
 	WaterCount    int
 	FlowingCount  int
 }
 
 func NewAquifer(input []Line) Aquifer {
-	a := Aquifer{
-		Min: util.MaxVec2D(),
-		Max: util.MinVec2D(),
-	}
+	a := Aquifer{Bounds: util.EmptyBox[util.Vec2D]()}
 	// Establish the boundary of the map
 	for _, line := range input {
 		//fmt.Println("line:", line)
-		a.Min.MinInPlace(line.Start)
-		a.Max.MaxInPlace(line.End)
+		a.Bounds.Extend(line.Start)
+		a.Bounds.Extend(line.End)
 	}
 	// Expand by 1 more each way in X direction, to allow flowing around edge features
-	a.Min.SubInPlace(util.Vec2D{1, 0})
-	a.Max.AddInPlace(util.Vec2D{1, 0})
+	a.Bounds = a.Bounds.Grow(util.Vec2D{1, 0})
 	// Width and height are inclusive of max
-	a.Width = a.Max.X - a.Min.X + 1
-	a.Height = a.Max.Y - a.Min.Y + 1
-	//fmt.Println("min", a.Min, "max", a.Max, "width", a.Width, "height", a.Height)
+	size := a.Bounds.Size()
+	a.Width, a.Height = size.X, size.Y
+	//fmt.Println("bounds", a.Bounds, "width", a.Width, "height", a.Height)
 	// Create and initialise map data
-	a.Data = util.NewByteGrid(a.Width, a.Height)
-	for x := 0; x < a.Width; x++ {
-		for y := 0; y < a.Height; y++ {
-			a.Data[x][y] = Sand
-		}
-	}
+	a.Data = util.NewGridWithBounds[byte](a.Bounds.Min, a.Bounds.Max, util.RowMajor)
+	a.Data.Initialize(Sand)
 	// Draw clay onto the map
 	for _, line := range input {
 		if line.Start.X == line.End.X {
@@ -72,25 +59,16 @@ func NewAquifer(input []Line) Aquifer {
 }
 
 func (a *Aquifer) String() string {
-	sb := strings.Builder{}
-	sb.Grow(a.Width * a.Height)
-	for y := 0; y < a.Height; y++ {
-		for x := 0; x < a.Width; x++ {
-			sb.WriteByte(a.Data[x][y])
-		}
-		sb.WriteByte('\n')
-	}
-	return sb.String()
+	r := util.GridRenderer[byte]{Glyph: util.ByteGlyph}
+	return r.Render(&a.Data)
 }
 
 func (a *Aquifer) Valid(p util.Vec2D) bool {
-	return p.X >= a.Min.X && p.X <= a.Max.X &&
-		p.Y >= a.Min.Y && p.Y <= a.Max.Y
+	return a.Data.Valid(p)
 }
 
 func (a *Aquifer) At(p util.Vec2D) *byte {
-	p.SubInPlace(a.Min)
-	return &a.Data[p.X][p.Y]
+	return a.Data.At(p)
 }
 
 func (a *Aquifer) FlowFrom(start util.Vec2D) {
@@ -182,44 +160,28 @@ func (a *Aquifer) FlowFrom(start util.Vec2D) {
 
 func (a *Aquifer) Count() int {
 	result := 0
-	for x := 0; x < a.Width; x++ {
-		for y := 0; y < a.Height; y++ {
-			switch a.Data[x][y] {
-			case Water, Flowing:
-				result++
-			}
+	a.Data.Traverse(func(p util.Vec2D, data *byte) {
+		switch *data {
+		case Water, Flowing:
+			result++
 		}
-	}
+	})
 	return result
 }
 
 func readInput(filename string) []Line {
-	var err error
-	file, err := os.Open(filename)
+	reader, err := util.OpenLines(filename)
 	util.Check(err)
-	reader := bufio.NewReader(file)
+	defer reader.Close()
 	result := make([]Line, 0)
-	for {
-		directionStr, err := reader.ReadString('=')
-		if err != nil {
-			break
+	for l := range reader.Lines() {
+		values := util.Ints(l.Text)
+		if len(values) != 3 {
+			util.Check(l.Errorf("expected 3 numbers, got %d", len(values)))
 		}
-		positionStr, err := reader.ReadString(',')
-		util.Check(err)
-		position, err := strconv.Atoi(strings.TrimRight(positionStr, ","))
-		util.Check(err)
-		reader.ReadString('=')
-		startStr, err := reader.ReadString('.')
-		util.Check(err)
-		start, err := strconv.Atoi(strings.TrimRight(startStr, "."))
-		util.Check(err)
-		reader.ReadByte()
-		endStr, err := reader.ReadString('\n')
-		util.Check(err)
-		end, err := strconv.Atoi(strings.TrimRight(endStr, "\r\n"))
-		util.Check(err)
+		position, start, end := values[0], values[1], values[2]
 		var line Line
-		if directionStr[0] == 'x' {
+		if l.Text[0] == 'x' {
 			line = Line{
 				util.Vec2D{position, start},
 				util.Vec2D{position, end},
@@ -232,16 +194,17 @@ func readInput(filename string) []Line {
 		}
 		result = append(result, line)
 	}
+	util.Check(reader.Err())
 	return result
 }
 
-func part1impl(logger *log.Logger, filename string) (water, flowing int) {
+func part1impl(logger *util.Logger, filename string) (water, flowing int) {
 	input := readInput(filename)
 	aquifer := NewAquifer(input)
 	//logger.Print("start:\n", aquifer.String())
 
 	// Flow the water
-	spring := util.Vec2D{500, aquifer.Min.Y}
+	spring := util.Vec2D{500, aquifer.Bounds.Min.Y}
 	aquifer.FlowFrom(spring)
 
 	//logger.Print("end:\n", aquifer.String())
@@ -255,11 +218,11 @@ func part1impl(logger *log.Logger, filename string) (water, flowing int) {
 }
 
 func init() {
-	//util.RegisterSolution("day17test1", func(logger *log.Logger) string {
+	//util.RegisterSolution("day17test1", func(logger *util.Logger) string {
 	//	water, flowing := part1impl(logger, "day17/input_test.txt")
 	//	return fmt.Sprint(water + flowing)
 	//})
-	util.RegisterSolution("day17", func(logger *log.Logger) string {
+	util.RegisterSolution("day17", func(logger *util.Logger) string {
 		water, flowing := part1impl(logger, "day17/input.txt")
 		return fmt.Sprintf("part1 = %d , part2 = %d", water + flowing, water)
 	})
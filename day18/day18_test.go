@@ -0,0 +1,23 @@
+package day18
+
+import (
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"os"
+	"testing"
+)
+
+func TestPart2Impl(t *testing.T) {
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
+	// Durations before the cycle starts have to be simulated in full, like part 1
+	for _, filename := range []string{"input_test.txt", "input.txt"} {
+		for _, duration := range []int{0, 1, 10} {
+			trees, lumberyards := part1impl(logger, filename, duration)
+			if value := part2impl(logger, filename, duration); value != trees*lumberyards {
+				t.Errorf("%s at t = %d: expected %d, got %d", filename, duration, trees*lumberyards, value)
+			}
+		}
+	}
+	if value := part2impl(logger, "input_test.txt", 10); value != 1147 {
+		t.Errorf("expected 1147, got %d", value)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/elfcode"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"os"
 )
 
@@ -21,7 +20,7 @@ func readInput(filename string) elfcode.Program {
 /*
 Run the program, emulating the instructions, and return the final state.
  */
-func emulated(logger *log.Logger, filename string, initialState elfcode.Registers) elfcode.Registers {
+func emulated(logger *util.Logger, filename string, initialState elfcode.Registers) elfcode.Registers {
 	program := readInput(filename)
 	state, _ := program.Run(initialState)
 	return state
@@ -30,7 +29,7 @@ func emulated(logger *log.Logger, filename string, initialState elfcode.Register
 /*
 A re-implementation of what the instructions in input.txt do: sum the factors of a number.
  */
-func translated(logger *log.Logger, seed int) int {
+func translated(logger *util.Logger, seed int) int {
 	var c int
 	if seed == 0 {
 		c = 877
@@ -51,7 +50,7 @@ func translated(logger *log.Logger, seed int) int {
 /*
 A faster re-implementation that takes O(n) time instead of O(n^2).
  */
-func translatedOptimised(logger *log.Logger, seed int) int {
+func translatedOptimised(logger *util.Logger, seed int) int {
 	var c int
 	if seed == 0 {
 		c = 877
@@ -71,27 +70,27 @@ func translatedOptimised(logger *log.Logger, seed int) int {
 }
 
 func init() {
-	//util.RegisterSolution("day19test1emu", func(logger *log.Logger) string {
+	//util.RegisterSolution("day19test1emu", func(logger *util.Logger) string {
 	//	return fmt.Sprint(emulated(logger, "day19/input_test.txt", Registers{}))
 	//})
 
-	//util.RegisterSolution("day19part1emu", func(logger *log.Logger) string {
+	//util.RegisterSolution("day19part1emu", func(logger *util.Logger) string {
 	//	return fmt.Sprint(emulated(logger, "day19/input.txt", elfcode.Registers{})[0])
 	//})
-	//util.RegisterSolution("day19part1trans", func(logger *log.Logger) string {
+	//util.RegisterSolution("day19part1trans", func(logger *util.Logger) string {
 	//	return fmt.Sprint(translated(logger, 0))
 	//})
-	util.RegisterSolution("day19part1opt", func(logger *log.Logger) string {
+	util.RegisterSolution("day19part1opt", func(logger *util.Logger) string {
 		return fmt.Sprint(translatedOptimised(logger, 0))
 	})
 
-	//util.RegisterSolution("day19part2emu", func(logger *log.Logger) string {
+	//util.RegisterSolution("day19part2emu", func(logger *util.Logger) string {
 	//	return fmt.Sprint(emulated(logger, "day19/input.txt", Registers{1})[0])
 	//})
-	//util.RegisterSolution("day19part2trans", func(logger *log.Logger) string {
+	//util.RegisterSolution("day19part2trans", func(logger *util.Logger) string {
 	//	return fmt.Sprint(translated(logger, 1))
 	//})
-	util.RegisterSolution("day19part2opt", func(logger *log.Logger) string {
+	util.RegisterSolution("day19part2opt", func(logger *util.Logger) string {
 		return fmt.Sprint(translatedOptimised(logger, 1))
 	})
 }
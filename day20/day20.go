@@ -3,7 +3,6 @@ package day20
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"strings"
 )
 
@@ -249,7 +248,7 @@ func EnumerateExpression(expr Expr, visitPartial Visitor, visitFinal Visitor) {
 }
 
 func SimplifyPath(directions string) string {
-	stack := util.NewByteStack(len(directions))
+	stack := util.NewStack[byte](len(directions))
 	for i := range directions {
 		stack.Push(directions[i])
 		// Remove clockwise/anti-clockwise cycles
@@ -338,7 +337,7 @@ func RoomStats(regex string, threshold int) (int, int) {
 }
 
 func init() {
-	util.RegisterSolution("day20", func(logger *log.Logger) string {
+	util.RegisterSolution("day20", func(logger *util.Logger) string {
 		lines, err := util.ReadLinesFromFile("day20/input.txt")
 		util.Check(err)
 		maxDistance, thresholdCount := RoomStats(lines[0], 1000)
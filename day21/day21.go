@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/elfcode"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"os"
 )
 
@@ -68,7 +67,7 @@ func generateValues(cb func(d int) (halt bool)) {
 Finding the value that halts after the fewest instructions means finding the first value for
 register 3 that is compared to register 0.
  */
-func part1impl(logger *log.Logger, filename string) int {
+func part1impl(logger *util.Logger, filename string) int {
 	// Reverse-engineer the value
 	var value int
 	generateValues(func(d int) bool {
@@ -103,7 +102,7 @@ problem. This implementation runs with this assumption and further assumes that
 contains each value once. Therefore, it terminates when a value is seen for a second time, and
 assumes the previous value was the end of the cycle.
  */
-func part2impl_slow(logger *log.Logger, filename string) int {
+func part2impl_slow(logger *util.Logger, filename string) int {
 	reader, err := os.Open(filename)
 	util.Check(err)
 	program := elfcode.ParseProgram(reader, 6)
@@ -124,7 +123,7 @@ func part2impl_slow(logger *log.Logger, filename string) int {
 	}
 
 	// Get the first value (should be the same as part 1)
-	seen := make(map[int]struct{})
+	seen := util.NewSet[int]()
 	prev := 0
 	count := 0
 	// Look for a cycle in the sequence of values, then use the last value in the cycle - any other value in
@@ -135,11 +134,11 @@ func part2impl_slow(logger *log.Logger, filename string) int {
 		if count % 100 == 0 {
 			logger.Printf("iteration %d\n", count)
 		}
-		if _, ok := seen[value]; ok {
+		if seen.Contains(value) {
 			break
 		}
 		prev = value
-		seen[value] = struct{}{}
+		seen.Add(value)
 	}
 	logger.Printf("repeat detected after %d iterations, %d instructions, last value before repeat = %d\n",
 		count, processor.InstructionCount, prev)
@@ -150,17 +149,17 @@ func part2impl_slow(logger *log.Logger, filename string) int {
 This implements the same solution as above, but implemented in Go instead of elfcode.
 It's about 30000x faster.
  */
-func part2impl_opt(logger *log.Logger, filename string) int {
-	seen := make(map[int]struct{})
+func part2impl_opt(logger *util.Logger, filename string) int {
+	seen := util.NewSet[int]()
 	prev := 0
 	count := 0
 	generateValues(func(d int) bool {
 		count++
-		if _, ok := seen[d]; ok {
+		if seen.Contains(d) {
 			return true
 		}
 		prev = d
-		seen[d] = struct{}{}
+		seen.Add(d)
 		return false
 	})
 	logger.Printf("repeat detected after %d iterations, last value before repeat = %d\n", count, prev)
@@ -168,13 +167,13 @@ func part2impl_opt(logger *log.Logger, filename string) int {
 }
 
 func init() {
-	util.RegisterSolution("day21part1", func(logger *log.Logger) string {
+	util.RegisterSolution("day21part1", func(logger *util.Logger) string {
 		return fmt.Sprint(part1impl(logger, "day21/input.txt"))
 	})
-	//util.RegisterSolution("day21part2slow", func(logger *log.Logger) string {
+	//util.RegisterSolution("day21part2slow", func(logger *util.Logger) string {
 	//	return fmt.Sprint(part2impl_slow(logger, "day21/input.txt"))
 	//})
-	util.RegisterSolution("day21part2opt", func(logger *log.Logger) string {
+	util.RegisterSolution("day21part2opt", func(logger *util.Logger) string {
 		return fmt.Sprint(part2impl_opt(logger, "day21/input.txt"))
 	})
 }
@@ -3,7 +3,6 @@ package day22
 import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 )
 
 const (
@@ -27,24 +26,14 @@ type State struct {
 }
 
 func (s1 State) LessThan(s2 State) bool {
-	switch {
-	case s1.Position.Y < s2.Position.Y:
-		return true
-	case s1.Position.Y > s2.Position.Y:
-		return false
-	case s1.Position.X < s2.Position.X:
-		return true
-	case s1.Position.X > s2.Position.X:
-		return false
-	case s1.Equipment < s2.Equipment:
-		return true
-	default:
-		return false
+	if s1.Position != s2.Position {
+		return s1.Position.ReadingLess(s2.Position)
 	}
+	return s1.Equipment < s2.Equipment
 }
 
-func MakeErosionMap(depth int, target util.Vec2D, scale util.Vec2D) util.IntGrid {
-	erosion := util.NewIntGrid(target.X*scale.X+1, target.Y*scale.Y+1)
+func MakeErosionMap(depth int, target util.Vec2D, scale util.Vec2D) util.Grid[int] {
+	erosion := util.NewGrid[int](target.X*scale.X+1, target.Y*scale.Y+1)
 
 	geologicIndex := func(p util.Vec2D) int {
 		switch {
@@ -74,8 +63,8 @@ func MakeErosionMap(depth int, target util.Vec2D, scale util.Vec2D) util.IntGrid
 	return erosion
 }
 
-func MakeTerrainMap(erosion util.IntGrid) util.ByteGrid {
-	terrain := util.NewByteGrid(erosion.Width(), erosion.Height())
+func MakeTerrainMap(erosion util.Grid[int]) util.Grid[byte] {
+	terrain := util.NewGrid[byte](erosion.Width(), erosion.Height())
 	erosion.Traverse(func(p util.Vec2D, data *int) {
 		*terrain.At(p) = byte(*data % 3)
 	})
@@ -85,7 +74,7 @@ func MakeTerrainMap(erosion util.IntGrid) util.ByteGrid {
 /*
 Sum the terrain/risk value of every square, in a map from (0, 0) to target (inclusive).
 */
-func part1impl(logger *log.Logger, depth int, target util.Vec2D) int {
+func part1impl(logger *util.Logger, depth int, target util.Vec2D) int {
 	erosion := MakeErosionMap(depth, target, util.Vec2D{1, 1})
 	terrain := MakeTerrainMap(erosion)
 
@@ -97,17 +86,19 @@ func part1impl(logger *log.Logger, depth int, target util.Vec2D) int {
 	return sum
 }
 
-//go:generate genny -in=../util/astar.go -out=gen-astar.go -pkg=day22 gen "SearchNode=State"
 /*
 Find the shortest path from (0, 0) to target, taking equipment into account.
 */
-func part2impl(logger *log.Logger, depth int, target util.Vec2D) int {
+func part2impl(logger *util.Logger, depth int, target util.Vec2D) int {
+	t := util.NewTimer(logger, "")
+	defer t.LogReport()
 	scale := util.Vec2D{1, 1}
-	var erosion util.IntGrid
-	var terrain util.ByteGrid
+	var erosion util.Grid[int]
+	var terrain util.Grid[byte]
 	regenerate := func() {
-		erosion = MakeErosionMap(depth, target, scale)
-		terrain = MakeTerrainMap(erosion)
+		defer t.Start("regenerate").End()
+		t.Time("erosion", func() { erosion = MakeErosionMap(depth, target, scale) })
+		t.Time("terrain", func() { terrain = MakeTerrainMap(erosion) })
 	}
 	regenerate()
 
@@ -174,7 +165,7 @@ func part2impl(logger *log.Logger, depth int, target util.Vec2D) int {
 		return n2.Position.Sub(n1.Position).Manhattan()
 	}
 
-	search := AStarSearchContext{
+	search := util.AStarSearchContext[State]{
 		Start: State{
 			Position:  util.Vec2D{0, 0},
 			Equipment: Torch,
@@ -190,25 +181,22 @@ func part2impl(logger *log.Logger, depth int, target util.Vec2D) int {
 		Heuristic:    heuristicFunc,
 		Cost:         costFunc,
 		TieBreak:     State.LessThan,
+		Stats:        &util.SearchStats{},
 	}
 
-	path, err := AStarSearch(&search)
+	span := t.Start("search")
+	_, cost, err := util.AStarSearch(&search)
+	span.End()
 	util.Check(err)
-
-	prev := search.Start
-	cost := 0
-	for _, next := range path {
-		cost += search.Cost(prev, next)
-		prev = next
-	}
+	logger.Printf("search: %v, final map scale %v", *search.Stats, scale)
 	return cost
 }
 
 func init() {
-	util.RegisterSolution("day22part1", func(logger *log.Logger) string {
+	util.RegisterSolution("day22part1", func(logger *util.Logger) string {
 		return fmt.Sprint(part1impl(logger, 3879, util.Vec2D{8, 713}))
 	})
-	util.RegisterSolution("day22part2", func(logger *log.Logger) string {
+	util.RegisterSolution("day22part2", func(logger *util.Logger) string {
 		return fmt.Sprint(part2impl(logger, 3879, util.Vec2D{8, 713}))
 	})
 }
@@ -2,13 +2,12 @@ package day22
 
 import (
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"os"
 	"testing"
 )
 
 func TestPart1Impl(t *testing.T) {
-	logger := log.New(os.Stdout, "", 0)
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
 	tables := []struct {
 		depth     int
 		target    util.Vec2D
@@ -35,7 +34,7 @@ func TestPart1Impl(t *testing.T) {
 }
 
 func TestPart2Impl(t *testing.T) {
-	logger := log.New(os.Stdout, "", 0)
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
 	tables := []struct {
 		depth     int
 		target    util.Vec2D
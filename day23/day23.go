@@ -1,15 +1,10 @@
 package day23
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"log"
 	"math/rand"
-	"os"
 	"sort"
-	"strconv"
-	"strings"
 )
 
 type Nanobot struct {
@@ -21,34 +16,14 @@ func (b *Nanobot) IsInRange(p util.Vec3D) bool {
 	return b.Position.Sub(p).Manhattan() <= b.Range
 }
 
+// The space within range of the nanobot
+func (b *Nanobot) Octahedron() util.Octahedron {
+	return util.Octahedron{b.Position, b.Range}
+}
+
 func readNanobots(filename string) []Nanobot {
-	file, err := os.Open(filename)
+	result, err := util.ReadRecordsFromFile[Nanobot](filename, "pos=<{Position.X},{Position.Y},{Position.Z}>, r={Range}")
 	util.Check(err)
-	reader := bufio.NewReader(file)
-	result := make([]Nanobot, 0)
-	for {
-		nanobot := Nanobot{}
-		var s string
-		var err error
-		if _, err := reader.ReadString('<'); err != nil {
-			break
-		}
-		s, err = reader.ReadString(',')
-		nanobot.Position.X, err = strconv.Atoi(strings.TrimRight(s, ","))
-		util.Check(err)
-		s, err = reader.ReadString(',')
-		nanobot.Position.Y, err = strconv.Atoi(strings.TrimRight(s, ","))
-		util.Check(err)
-		s, err = reader.ReadString('>')
-		nanobot.Position.Z, err = strconv.Atoi(strings.TrimRight(s, ">"))
-		util.Check(err)
-		s, err = reader.ReadString('=')
-		util.Check(err)
-		s, err = reader.ReadString('\n')
-		nanobot.Range, err = strconv.Atoi(strings.TrimRight(s, "\r\n"))
-		util.Check(err)
-		result = append(result, nanobot)
-	}
 	return result
 }
 
@@ -57,7 +32,7 @@ type Location struct {
 	InRangeOf int
 }
 
-func part1impl(logger *log.Logger, filename string) int {
+func part1impl(logger *util.Logger, filename string) int {
 	nanobots := readNanobots(filename)
 
 	// Find nanobot with largest range
@@ -81,33 +56,163 @@ func part1impl(logger *log.Logger, filename string) int {
 	return count
 }
 
+/*
+A region of the search space, with an upper bound on how many nanobots are in
+range of any location in it and a lower bound on the distance of any location
+in it from (0, 0, 0).
+*/
+type octreeNode struct {
+	Box       util.Box3D
+	InRangeOf int
+	Distance  int
+}
+
+// Is a more promising than b?
+func (a *octreeNode) LessThan(b *octreeNode) bool {
+	switch {
+	case a.InRangeOf != b.InRangeOf:
+		return a.InRangeOf > b.InRangeOf
+	case a.Distance != b.Distance:
+		return a.Distance < b.Distance
+	default:
+		// Prefer smaller boxes, to get down to a single location sooner
+		return a.longestSide() < b.longestSide()
+	}
+}
+
+// Size of the box, by its longest side, because the volume of the biggest boxes overflows an int
+func (a *octreeNode) longestSide() int {
+	size := a.Box.Size()
+	return util.MaxInt(size.X, size.Y, size.Z)
+}
+
+/*
+Finds the best location exactly, by octree subdivision of the space around the
+nanobots:
+
+- The number of nanobots whose range intersects a box is an upper bound on the
+  number in range of any location in the box
+- The distance from (0, 0, 0) to the box is a lower bound on the distance of
+  any location in the box
+- Boxes are split into octants, most promising box first, so the first box to
+  be reduced to a single location is the best location
+
+Also returns the number of boxes that were split.
+*/
+func searchOctree(nanobots []Nanobot) (Location, int) {
+	bounds := util.EmptyBox[util.Vec3D]()
+	for i := range nanobots {
+		octahedron := nanobots[i].Octahedron().BoundingBox()
+		bounds.Extend(octahedron.Min)
+		bounds.Extend(octahedron.Max)
+	}
+	origin := util.Vec3D{0, 0, 0}
+
+	newNode := func(box util.Box3D) *octreeNode {
+		node := &octreeNode{Box: box, Distance: box.Distance(origin)}
+		for i := range nanobots {
+			if nanobots[i].Octahedron().IntersectsBox(box) {
+				node.InRangeOf++
+			}
+		}
+		return node
+	}
+
+	queue := util.NewPriorityQueue((*octreeNode).LessThan)
+	queue.Push(newNode(bounds))
+	splits := 0
+	for {
+		node, _ := queue.Pop()
+		if node.Box.Min == node.Box.Max {
+			return Location{node.Box.Min, node.InRangeOf}, splits
+		}
+		splits++
+		for _, box := range node.Box.Split() {
+			queue.Push(newNode(box))
+		}
+	}
+}
+
+func part2impl(logger *util.Logger, filename string) int {
+	nanobots := readNanobots(filename)
+	best, splits := searchOctree(nanobots)
+	logger.Printf("best location after splitting %d boxes: %+v, distance=%d", splits, best, best.Position.Manhattan())
+	return best.Position.Manhattan()
+}
+
+// Tunable parameters for part2random
+type EvolutionParams struct {
+	// Number of best locations preserved each generation (0 for the number of nanobots)
+	Keep int
+	// Number of new locations generated from each preserved location
+	Multiply int
+	// Initial perturbation limit (0 for the Manhattan size of the search space)
+	Energy int
+	// Energy is divided by this each generation
+	EnergyDecay int
+	// Number of generations without improvement before giving up
+	Threshold int
+}
+
+// Check the parameters can make progress, i.e. that the search narrows and eventually stops
+func (p EvolutionParams) Validate() error {
+	switch {
+	case p.Keep < 0:
+		return fmt.Errorf("keep must not be negative, got %d", p.Keep)
+	case p.Multiply < 1:
+		return fmt.Errorf("multiply must be at least 1, got %d", p.Multiply)
+	case p.Energy < 0:
+		return fmt.Errorf("energy must not be negative, got %d", p.Energy)
+	case p.EnergyDecay < 2:
+		return fmt.Errorf("energy decay must be at least 2, got %d", p.EnergyDecay)
+	case p.Threshold < 1:
+		return fmt.Errorf("threshold must be at least 1, got %d", p.Threshold)
+	}
+	return nil
+}
+
+var DefaultEvolutionParams = EvolutionParams{
+	Keep:        0,
+	Multiply:    5,
+	Energy:      0,
+	EnergyDecay: 2,
+	Threshold:   10,
+}
+
 /*
 Applies an "evolutionary strategy" to discover the optimum location, along the following lines:
 
 - The fitness comparison optimises for number of nanobots in range, then for distance from (0, 0, 0)
 - The initial population is the location of every nanobot
 - Each generation:
-	- Preserve `keep` best locations from previous generation
-	- Generate `multiply` new (unique) locations for each preserved location
+	- Preserve `Keep` best locations from previous generation
+	- Generate `Multiply` new (unique) locations for each preserved location
 	- New locations are perturbed by a random Manhattan distance, which is randomly partitioned into (X, Y, Z)
 	- If the best location from this generation is fitter than from last generation, record it
-- The perturbation is limited by `energy`, decreasing exponentially from the size of the entire search volume
-- When the best location hasn't been surpassed in `threshold` generations, terminate the algorithm
+- The perturbation is limited by `Energy`, decreasing exponentially (by `EnergyDecay`) from the size of the
+  entire search volume
+- When the best location hasn't been surpassed in `Threshold` generations, terminate the algorithm
+
+All randomness comes from rng, so the result is reproducible for the same seed.
 
 (This isn't a genetic algorithm, because it has mutation and selection but no crossover.)
+
+This is kept for comparison with searchOctree, but isn't guaranteed to find the best location.
  */
-func part2impl(logger *log.Logger, filename string) int {
+func part2random(logger *util.Logger, filename string, rng *rand.Rand, params EvolutionParams) (int, error) {
+	if err := params.Validate(); err != nil {
+		return 0, err
+	}
 	nanobots := readNanobots(filename)
-	min, max := util.MaxVec3D(), util.MinVec3D()
+	bounds := util.EmptyBox[util.Vec3D]()
 	population := make([]Location, 0, len(nanobots))
 
 	// Find extend of coordinate space
 	for _, bot := range nanobots {
-		min.MinInPlace(bot.Position)
-		max.MaxInPlace(bot.Position)
+		bounds.Extend(bot.Position)
 	}
-	size := max.Sub(min).Add(util.Vec3D{1, 1, 1})
-	logger.Printf("search space: %v to %v, size = %v, volume = %d", min, max, size, size.Volume())
+	size := bounds.Size()
+	logger.Printf("search space: %v to %v, size = %v, volume = %d", bounds.Min, bounds.Max, size, bounds.Volume())
 
 	evaluateFunc := func(p util.Vec3D) int {
 		count := 0
@@ -140,11 +245,20 @@ func part2impl(logger *log.Logger, filename string) int {
 	sort.Slice(population, locationSort(population))
 	//logger.Printf("most connected positions: %v...", population[:util.MinInt(10, len(population)-1)])
 
-	energy := max.Sub(min).Manhattan()
-	threshold := 10
-	keep := len(nanobots)
-	multiply := 5
+	energy := params.Energy
+	if energy == 0 {
+		energy = bounds.Max.Sub(bounds.Min).Manhattan()
+	}
+	// Still need to perturb by at least 1, even if every nanobot is in the same place
+	energy = util.MaxInt(energy, 1)
+	threshold := params.Threshold
+	keep := params.Keep
+	if keep == 0 || keep > len(nanobots) {
+		keep = len(nanobots)
+	}
+	multiply := params.Multiply
 	generate := keep * multiply
+	logger.Printf("evolution parameters: %+v", params)
 
 	best := Location{}
 	bestSurvival := 0	// How long the best location has remained the best location
@@ -153,17 +267,17 @@ func part2impl(logger *log.Logger, filename string) int {
 	for ; bestSurvival < threshold; generation++ {
 		//logger.Printf("new generation with energy=%d threshold=%d keep=%d generate=%d", energy, threshold, keep, generate)
 		newPopulation := make([]Location, 0, keep + generate)
-		newPopulationSet := make(map[Location]bool)
+		newPopulationSet := util.NewSet[Location]()
 		for _, loc := range population[0:keep] {
 			// Keep the existing location
 			newPopulation = append(newPopulation, loc)
-			newPopulationSet[loc] = true
+			newPopulationSet.Add(loc)
 			for i, generated := 0, 0; generated < multiply && i < multiply*multiply; i++ {
 				// Pick a random manhattan distance to perturb by (at least 1)
-				distance := rand.Intn(energy)+1
+				distance := rng.Intn(energy)+1
 				// Partition the distance into random X, Y and Z amounts
-				firstPartition := rand.Intn(distance+1)
-				secondPartition := rand.Intn(distance+1)
+				firstPartition := rng.Intn(distance+1)
+				secondPartition := rng.Intn(distance+1)
 				if firstPartition > secondPartition {
 					firstPartition, secondPartition = secondPartition, firstPartition
 				}
@@ -173,23 +287,20 @@ func part2impl(logger *log.Logger, filename string) int {
 					distance - secondPartition,
 				}
 				// Randomise directions
-				if rand.Float32() >= 0.5 { perturb.X *= -1 }
-				if rand.Float32() >= 0.5 { perturb.Y *= -1 }
-				if rand.Float32() >= 0.5 { perturb.Z *= -1 }
-				// Create new position
-				newPos := loc.Position.Add(perturb)
-				// Clamp it inside the bounds of the known universe
-				newPos.MaxInPlace(min)
-				newPos.MinInPlace(max)
+				if rng.Float32() >= 0.5 { perturb.X *= -1 }
+				if rng.Float32() >= 0.5 { perturb.Y *= -1 }
+				if rng.Float32() >= 0.5 { perturb.Z *= -1 }
+				// Create new position, clamped inside the bounds of the known universe
+				newPos := bounds.Clamp(loc.Position.Add(perturb))
 				// Create and add the new location
 				//logger.Printf("adding new candidate: original=%v, perturb=%v, new=%v", loc.Position, perturb, newPos)
 				newLoc := Location{
 					Position: newPos,
 					InRangeOf: evaluateFunc(newPos),
 				}
-				if !newPopulationSet[newLoc] {
+				if !newPopulationSet.Contains(newLoc) {
 					newPopulation = append(newPopulation, newLoc)
-					newPopulationSet[newLoc] = true
+					newPopulationSet.Add(newLoc)
 					generated++
 				}
 			}
@@ -213,7 +324,7 @@ func part2impl(logger *log.Logger, filename string) int {
 
 		// Exponentially reduce the randomness
 		if energy > 1 {
-			energy /= 2
+			energy = util.MaxInt(energy/params.EnergyDecay, 1)
 		}
 	}
 
@@ -229,20 +340,25 @@ func part2impl(logger *log.Logger, filename string) int {
 	}
 	logger.Printf("found %d best locations, %+v", bestCount, population[bestCount-1])
 
-	return best.Position.Manhattan()
+	return best.Position.Manhattan(), nil
 }
 
 func init() {
-	//util.RegisterSolution("day23test1", func(logger *log.Logger) string {
+	//util.RegisterSolution("day23test1", func(logger *util.Logger) string {
 	//	return fmt.Sprint(part1impl(logger, "day23/input_test.txt"))
 	//})
-	util.RegisterSolution("day23part1", func(logger *log.Logger) string {
+	util.RegisterSolution("day23part1", func(logger *util.Logger) string {
 		return fmt.Sprint(part1impl(logger, "day23/input.txt"))
 	})
-	//util.RegisterSolution("day23test2", func(logger *log.Logger) string {
+	//util.RegisterSolution("day23test2", func(logger *util.Logger) string {
 	//	return fmt.Sprint(part2impl(logger, "day23/input_test2.txt"))
 	//})
-	util.RegisterSolution("day23part2", func(logger *log.Logger) string {
+	util.RegisterSolution("day23part2", func(logger *util.Logger) string {
 		return fmt.Sprint(part2impl(logger, "day23/input.txt"))
 	})
+	util.RegisterSolution("day23part2random", func(logger *util.Logger) string {
+		distance, err := part2random(logger, "day23/input.txt", util.NewRand(logger), DefaultEvolutionParams)
+		util.Check(err)
+		return fmt.Sprint(distance)
+	})
 }
@@ -0,0 +1,61 @@
+package day23
+
+import (
+	"github.com/alanbriolat/AdventOfCode2018/util"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestPart1Impl(t *testing.T) {
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
+	if count := part1impl(logger, "input_test.txt"); count != 7 {
+		t.Errorf("expected 7, got %d", count)
+	}
+}
+
+func TestPart2Impl(t *testing.T) {
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
+	if distance := part2impl(logger, "input_test2.txt"); distance != 36 {
+		t.Errorf("expected 36, got %d", distance)
+	}
+}
+
+func TestPart2RandomReproducible(t *testing.T) {
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
+	first, err := part2random(logger, "input_test2.txt", rand.New(rand.NewSource(42)), DefaultEvolutionParams)
+	util.Check(err)
+	second, err := part2random(logger, "input_test2.txt", rand.New(rand.NewSource(42)), DefaultEvolutionParams)
+	util.Check(err)
+	if first != second {
+		t.Errorf("expected same result for same seed, got %d and %d", first, second)
+	}
+}
+
+func TestPart2RandomSinglePoint(t *testing.T) {
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
+	// Nothing to search, because every nanobot is at the same place
+	filename := t.TempDir() + "/input.txt"
+	util.Check(os.WriteFile(filename, []byte("pos=<1,2,3>, r=4\npos=<1,2,3>, r=1\n"), 0644))
+	if distance, err := part2random(logger, filename, rand.New(rand.NewSource(42)), DefaultEvolutionParams); err != nil || distance != 6 {
+		t.Errorf("expected 6, got %d (%v)", distance, err)
+	}
+}
+
+func TestEvolutionParams(t *testing.T) {
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
+	invalid := []func(p *EvolutionParams){
+		func(p *EvolutionParams) { p.EnergyDecay = 0 },
+		func(p *EvolutionParams) { p.EnergyDecay = 1 },
+		func(p *EvolutionParams) { p.Multiply = 0 },
+		func(p *EvolutionParams) { p.Threshold = 0 },
+		func(p *EvolutionParams) { p.Keep = -1 },
+	}
+	for i, change := range invalid {
+		params := DefaultEvolutionParams
+		change(&params)
+		if _, err := part2random(logger, "input_test2.txt", rand.New(rand.NewSource(42)), params); err == nil {
+			t.Errorf("%d: expected error for %+v", i, params)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/alanbriolat/AdventOfCode2018/util"
 	"github.com/alecthomas/participle"
-	"log"
 	"os"
 	"sort"
 )
@@ -95,6 +94,8 @@ func (g *Group) IsDead() bool {
 
 type Battle struct {
 	Groups []*Group
+	// Debug logs each fight, trace logs target selection
+	Log *util.Logger
 }
 
 func (b *ParsedBattle) ToBattle() *Battle {
@@ -116,6 +117,7 @@ func (b *ParsedBattle) ToBattle() *Battle {
 func (b *Battle) Copy() *Battle {
 	result := &Battle{
 		Groups: make([]*Group, len(b.Groups)),
+		Log:    b.Log,
 	}
 	for i, g := range b.Groups {
 		result.Groups[i] = g.Copy()
@@ -189,7 +191,7 @@ func (b *Battle) Fight() (immuneCount, infectionCount int) {
 			}
 		}
 		if target != nil {
-			//fmt.Printf("%+v will attack %+v\n", g, target.Group)
+			b.Log.Tracef("%+v will attack %+v", g, target.Group)
 			targeting[g] = target.Group
 			targeted[target.Group] = g
 		}
@@ -218,7 +220,7 @@ func (b *Battle) Fight() (immuneCount, infectionCount int) {
 
 func (b *Battle) Run() (immuneCount, infectionCount int) {
 	immuneCount, infectionCount = b.CountUnits(false), b.CountUnits(true)
-	//fmt.Printf("start: immune = %d, infection = %d\n", immuneCount, infectionCount)
+	b.Log.Debugf("start: immune = %d, infection = %d", immuneCount, infectionCount)
 	for i := 0; immuneCount > 0 && infectionCount > 0; i++ {
 		newImmuneCount, newInfectionCount := b.Fight()
 		if newImmuneCount == immuneCount && newInfectionCount == infectionCount {
@@ -227,7 +229,7 @@ func (b *Battle) Run() (immuneCount, infectionCount int) {
 		} else {
 			immuneCount, infectionCount = newImmuneCount, newInfectionCount
 		}
-		//fmt.Printf("fight %d: immune = %d, infection = %d\n", i+1, immuneCount, infectionCount)
+		b.Log.Debugf("fight %d: immune = %d, infection = %d", i+1, immuneCount, infectionCount)
 	}
 	return
 }
@@ -276,8 +278,9 @@ func parseBattle(filename string) *Battle {
 	return parsedBattle.ToBattle()
 }
 
-func part1impl(logger *log.Logger, filename string) int {
+func part1impl(logger *util.Logger, filename string) int {
 	battle := parseBattle(filename)
+	battle.Log = logger.Sub("battle")
 	immuneCount, infectionCount := battle.Run()
 	// One of these should be 0
 	return immuneCount + infectionCount
@@ -286,12 +289,13 @@ func part1impl(logger *log.Logger, filename string) int {
 /*
 Do a binary search on immune system boost amounts to find the smallest amount where the immune system wins.
  */
-func part2impl(logger *log.Logger, filename string) int {
+func part2impl(logger *util.Logger, filename string) int {
 	prototype := parseBattle(filename)
 
 	// Evaluate if `boost` is sufficient to win
 	evaluationFunc := func(boost int) (int, bool) {
 		battle := prototype.Copy()
+		battle.Log = logger.Sub("battle").With("boost", boost)
 		battle.BoostImmuneSystem(boost)
 		immuneCount, infectionCount := battle.Run()
 		return immuneCount, infectionCount == 0
@@ -315,20 +319,20 @@ func part2impl(logger *log.Logger, filename string) int {
 			start = end
 		}
 	}
-	//logger.Printf("minimum boost is between %d and %d\n", start, end)
+	logger.Debugf("minimum boost is between %d and %d", start, end)
 
 	// Optimise `start` to the last value that loses and `end` to the first value that wins
 	for {
 		boost := (start + end) / 2
 		immuneCount, win := evaluationFunc(boost)
-		//logger.Printf("evaluated %d, immune = %d, win = %v", boost, immuneCount, win)
+		logger.Debugf("evaluated %d, immune = %d, win = %v", boost, immuneCount, win)
 		if win {
 			end = boost
 			result = immuneCount
 		} else {
 			start = boost
 		}
-		//logger.Printf("minimum boost is between %d and %d\n", start, end)
+		logger.Debugf("minimum boost is between %d and %d", start, end)
 		// Once the values are adjacent, `end` should be the lowest boost that wins
 		if end - start == 1 {
 			logger.Printf("binary search found boost = %d, immune = %d\n", end, result)
@@ -351,16 +355,16 @@ func part2impl(logger *log.Logger, filename string) int {
 }
 
 func init() {
-	//util.RegisterSolution("day24test1", func(logger *log.Logger) string {
+	//util.RegisterSolution("day24test1", func(logger *util.Logger) string {
 	//	return fmt.Sprint(part1impl(logger, "day24/input_test.txt"))
 	//})
-	util.RegisterSolution("day24part1", func(logger *log.Logger) string {
+	util.RegisterSolution("day24part1", func(logger *util.Logger) string {
 		return fmt.Sprint(part1impl(logger, "day24/input.txt"))
 	})
-	//util.RegisterSolution("day24test2", func(logger *log.Logger) string {
+	//util.RegisterSolution("day24test2", func(logger *util.Logger) string {
 	//	return fmt.Sprint(part2impl(logger, "day24/input_test.txt"))
 	//})
-	util.RegisterSolution("day24part2", func(logger *log.Logger) string {
+	util.RegisterSolution("day24part2", func(logger *util.Logger) string {
 		return fmt.Sprint(part2impl(logger, "day24/input.txt"))
 	})
 }
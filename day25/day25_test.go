@@ -1,13 +1,13 @@
 package day25
 
 import (
-	"log"
+	"github.com/alanbriolat/AdventOfCode2018/util"
 	"os"
 	"testing"
 )
 
 func TestPart1Impl(t *testing.T) {
-	logger := log.New(os.Stdout, "", 0)
+	logger := util.NewLogger(os.Stdout, util.LevelInfo)
 	tables := []struct{
 		filename string
 		result int
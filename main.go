@@ -31,21 +31,42 @@ import (
 	_ "github.com/alanbriolat/AdventOfCode2018/day24"
 	_ "github.com/alanbriolat/AdventOfCode2018/day25"
 	"github.com/alanbriolat/AdventOfCode2018/util"
-	"io/ioutil"
-	"log"
 	"os"
 )
 
-var verbose = flag.Bool("v", false, "verbose logging")
+var verbose = flag.Bool("v", false, "verbose logging (same as -log-level=info)")
+var logLevel = flag.String("log-level", "none", "log `level` for solutions: none, info, debug or trace")
+var logFilter = flag.String("log-filter", "", "log levels for specific solutions, e.g. `day15=trace,day24=debug`")
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
+var traceFile = flag.String("trace", "", "write timing spans to `file` as Chrome trace JSON (see chrome://tracing)")
+var seed = flag.Int64("seed", 0, "random `seed` for solutions that use randomness (default: based on current time)")
 
 func main() {
-	mainLog := log.New(os.Stdout, "main: ", 0)
+	mainLog := util.NewLogger(os.Stdout, util.LevelInfo).Sub("main")
 	t := util.NewTimer(mainLog, "")
 	defer t.LogCheckpoint("ran all solutions")
 
 	flag.Parse()
 
+	if *watch {
+		watchSolutions(mainLog, flag.Args())
+		return
+	}
+
+	if *seed != 0 {
+		util.SetSeed(*seed)
+	}
+	mainLog.Printf("random seed: %d", util.GetSeed())
+
+	level, err := util.ParseLevel(*logLevel)
+	util.Check(err)
+	if *verbose && level < util.LevelInfo {
+		level = util.LevelInfo
+	}
+	filters, err := util.ParseLogFilters(*logFilter)
+	util.Check(err)
+	rootLog := util.NewLogger(os.Stdout, level, filters...)
+
 	only := make(map[string]struct{})
 	for _, name := range flag.Args() {
 		only[name] = struct{}{}
@@ -59,18 +80,25 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if *traceFile != "" {
+		util.StartTrace()
+		defer func() {
+			f, err := os.Create(*traceFile)
+			util.Check(err)
+			defer f.Close()
+			util.Check(util.WriteTrace(f))
+		}()
+	}
+
 	for _, s := range util.GetSolutions() {
 		if _, ok := only[s.Name]; len(only) > 0 && !ok {
 			continue
 		}
-		var logger *log.Logger
-		if *verbose {
-			logger = log.New(os.Stdout, s.Name + ": ", 0)
-		} else {
-			logger = log.New(ioutil.Discard, "", 0)
-		}
+		logger := rootLog.Sub(s.Name)
 		logger.Println("----------------")
+		span := t.Start(s.Name)
 		result := s.Run(logger)
+		span.End()
 		t.LogCheckpoint(fmt.Sprintf("%v answer: %v", s.Name, result))
 		logger.Println("----------------")
 	}
@@ -3,214 +3,469 @@ package util
 import (
 	"container/heap"
 	"fmt"
-	"github.com/cheekybits/genny/generic"
 	"math"
+	"time"
 )
 
-type SearchNode generic.Type
-
 // Priority queue based on https://golang.org/pkg/container/heap/#example__priorityQueue
-type SearchQueueItem struct {
-	value    SearchNode
-	priority func() int
+type searchQueueItem[N comparable] struct {
+	value    N
+	priority int
 	index    int
 }
 
-type SearchQueue struct {
-	ctx *AStarSearchContext
-	data []*SearchQueueItem
-}
-
-func NewSearchQueue(ctx *AStarSearchContext) SearchQueue {
-	return SearchQueue{
-		ctx,
-		make([]*SearchQueueItem, 0, ctx.NodeCountMax),
-	}
+type searchQueue[N comparable] struct {
+	tieBreak func(n1, n2 N) bool
+	data     []*searchQueueItem[N]
 }
 
-func (pq SearchQueue) Len() int {
+func (pq searchQueue[N]) Len() int {
 	return len(pq.data)
 }
 
-func (pq SearchQueue) Less(i, j int) bool {
+func (pq searchQueue[N]) Less(i, j int) bool {
 	x1, x2 := pq.data[i], pq.data[j]
-	p1, p2 := x1.priority(), x2.priority()
-	return p1 < p2 || p1 == p2 && pq.ctx.TieBreak(x1.value, x2.value)
+	p1, p2 := x1.priority, x2.priority
+	return p1 < p2 || p1 == p2 && pq.tieBreak(x1.value, x2.value)
 }
 
-func (pq SearchQueue) Swap(i, j int) {
+func (pq searchQueue[N]) Swap(i, j int) {
 	pq.data[i], pq.data[j] = pq.data[j], pq.data[i]
 	pq.data[i].index = i
 	pq.data[j].index = j
 }
 
-func (pq *SearchQueue) Push(x interface{}) {
+func (pq *searchQueue[N]) Push(x interface{}) {
 	n := len(pq.data)
-	item := x.(*SearchQueueItem)
+	item := x.(*searchQueueItem[N])
 	item.index = n
 	pq.data = append(pq.data, item)
 }
 
-func (pq *SearchQueue) Pop() interface{} {
+func (pq *searchQueue[N]) Pop() interface{} {
 	old := pq.data
 	n := len(old)
 	item := old[n-1]
 	item.index = -1
-	pq.data = old[0:n-1]
+	pq.data = old[0 : n-1]
 	return item
 }
 
-type AStarSearchContext struct {
+/*
+openSet is the "open set" of a best-first search: a priority queue of nodes
+that can also find the queue entry for a node so its priority can be changed.
+*/
+type openSet[N comparable] struct {
+	queue searchQueue[N]
+	items map[N]*searchQueueItem[N]
+}
+
+func newOpenSet[N comparable](sizeHint int, tieBreak func(n1, n2 N) bool) *openSet[N] {
+	return &openSet[N]{
+		queue: searchQueue[N]{tieBreak, make([]*searchQueueItem[N], 0, sizeHint)},
+		items: make(map[N]*searchQueueItem[N]),
+	}
+}
+
+func (s *openSet[N]) Len() int {
+	return s.queue.Len()
+}
+
+// Add n to the open set with the given priority, or change its priority if already present
+func (s *openSet[N]) Set(n N, priority int) {
+	if item, ok := s.items[n]; ok {
+		item.priority = priority
+		heap.Fix(&s.queue, item.index)
+	} else {
+		item := &searchQueueItem[N]{value: n, priority: priority}
+		heap.Push(&s.queue, item)
+		s.items[n] = item
+	}
+}
+
+func (s *openSet[N]) Peek() (N, int) {
+	item := s.queue.data[0]
+	return item.value, item.priority
+}
+
+func (s *openSet[N]) Pop() (N, int) {
+	item := heap.Pop(&s.queue).(*searchQueueItem[N])
+	delete(s.items, item.value)
+	return item.value, item.priority
+}
+
+/*
+AStarSearchContext describes a search problem over nodes of type N. The same
+context is accepted by AStarSearch, DijkstraSearch, BreadthFirstSearch and
+BidirectionalSearch, each of which uses a subset of the hooks.
+*/
+type AStarSearchContext[N comparable] struct {
 	// Initial state
-	Start SearchNode
+	Start N
 	// All allowable end states
-	Destinations []SearchNode
+	Destinations []N
 	// Estimate of number of possible states
 	NodeCountMax int
 	// All valid states adjacent to n
-	Adjacent func(n SearchNode) []SearchNode
-	// Estimate of cost to move from n1 to n2 (not necessarily adjacent)
-	Heuristic func(n1, n2 SearchNode) int
-	// Actual cost to move from n1 to n2 (adjacent)
-	Cost func(n1, n2 SearchNode) int
-	// Is n1 chosen first if it has the same path cost as n2?
-	TieBreak func(n1, n2 SearchNode) bool
+	Adjacent func(n N) []N
+	// Estimate of cost to move from n1 to n2 (not necessarily adjacent), nil means 0
+	Heuristic func(n1, n2 N) int
+	// Actual cost to move from n1 to n2 (adjacent), nil means 1
+	Cost func(n1, n2 N) int
+	// Is n1 chosen first if it has the same path cost as n2? nil means no preference
+	TieBreak func(n1, n2 N) bool
+	// If set, filled in with statistics when the search finishes
+	Stats *SearchStats
+	// If set, called for every change to the open set during the search
+	OnEvent func(e SearchEvent[N])
 }
 
-/*
-https://en.wikipedia.org/wiki/A*_search_algorithm
-*/
-func AStarSearch(ctx *AStarSearchContext) ([]SearchNode, error) {
-	if len(ctx.Destinations) == 0 {
-		return nil, fmt.Errorf("no destinations")
+// Start instrumenting a search, returning stats to update and a function to call when the search finishes
+func (ctx *AStarSearchContext[N]) instrument() (*SearchStats, func()) {
+	stats := &SearchStats{}
+	startedAt := time.Now()
+	return stats, func() {
+		if ctx.Stats != nil {
+			stats.Elapsed = time.Since(startedAt)
+			*ctx.Stats = *stats
+		}
 	}
+}
 
-	// f(n) = g(n) + h(n)
-	// g(n): cost to get to n from start
-	// h(n): estimate of cost from n to goal
-	gScore := make(map[SearchNode]int)
-	g := func(n SearchNode) int {
-		if result, ok := gScore[n]; ok {
-			return result
-		} else {
-			return math.MaxInt32
-		}
+func (ctx *AStarSearchContext[N]) emit(t SearchEventType, n N, cost int, openSetSize int) {
+	if ctx.OnEvent != nil {
+		ctx.OnEvent(SearchEvent[N]{t, n, cost, openSetSize})
 	}
-	fScore := make(map[SearchNode]int)
-	f := func(n SearchNode) int {
-		if result, ok := fScore[n]; ok {
-			return result
-		} else {
-			return math.MaxInt32
-		}
+}
+
+func (ctx *AStarSearchContext[N]) heuristic(n N) int {
+	if ctx.Heuristic == nil {
+		return 0
 	}
-	h := func(n SearchNode) int {
-		min := math.MaxInt32
-		for _, d := range ctx.Destinations {
-			if distance := ctx.Heuristic(n, d); distance < min {
-				min = distance
-			}
+	min := math.MaxInt32
+	for _, d := range ctx.Destinations {
+		if distance := ctx.Heuristic(n, d); distance < min {
+			min = distance
 		}
-		return min
 	}
+	return min
+}
 
-	// Priority queue of the "open set"
-	openSetQueue := NewSearchQueue(ctx)
-	heap.Init(&openSetQueue)
-	// Find "open set" queue item by search node value
-	openSetMap := make(map[SearchNode]*SearchQueueItem)
-	// Nodes already processed
-	closedSet := make(map[SearchNode]bool)
-	// Nodes queued or processed
-	visited := make(map[SearchNode]bool)
-
-	pushFunc := func(n SearchNode) {
-		item := &SearchQueueItem{
-			value: n,
-			priority: func() int {
-				return f(n)
-			},
-		}
-		heap.Push(&openSetQueue, item)
-		openSetMap[n] = item
-		visited[n] = true
+func (ctx *AStarSearchContext[N]) cost(n1, n2 N) int {
+	if ctx.Cost == nil {
+		return 1
 	}
+	return ctx.Cost(n1, n2)
+}
 
-	popFunc := func() SearchNode {
-		item := heap.Pop(&openSetQueue).(*SearchQueueItem)
-		delete(openSetMap, item.value)
-		closedSet[item.value] = true	// Don't visit this node again
-		return item.value
+func (ctx *AStarSearchContext[N]) tieBreak(n1, n2 N) bool {
+	if ctx.TieBreak == nil {
+		return false
 	}
+	return ctx.TieBreak(n1, n2)
+}
 
-	updateFunc := func(n SearchNode) {
-		if item, ok := openSetMap[n]; ok {
-			heap.Fix(&openSetQueue, item.index)
-		} else {
-			panic("AStarSearch: could not find openSetMap item")
+func (ctx *AStarSearchContext[N]) isDestination(n N) bool {
+	for _, d := range ctx.Destinations {
+		if n == d {
+			return true
 		}
 	}
+	return false
+}
+
+// Follow cameFrom links back from destination to start, returning the path from start to destination
+func tracePath[N comparable](cameFrom map[N]N, start, destination N) []N {
+	result := make([]N, 0)
+	next := destination
+	for next != start {
+		result = append(result, next)
+		next = cameFrom[next]
+	}
+	// Reverse the path, so it's from start to goal
+	for left, right := 0, len(result)-1; left < right; left, right = left+1, right-1 {
+		result[left], result[right] = result[right], result[left]
+	}
+	return result
+}
+
+/*
+AStarSearch finds the lowest cost path from ctx.Start to any of
+ctx.Destinations, returning the path (excluding the start node) and its total
+cost.
+
+https://en.wikipedia.org/wiki/A*_search_algorithm
+*/
+func AStarSearch[N comparable](ctx *AStarSearchContext[N]) ([]N, int, error) {
+	if len(ctx.Destinations) == 0 {
+		return nil, 0, fmt.Errorf("no destinations")
+	}
+	stats, done := ctx.instrument()
+	defer done()
+
+	// f(n) = g(n) + h(n)
+	// g(n): cost to get to n from start
+	// h(n): estimate of cost from n to goal
+	gScore := make(map[N]int)
+
+	// Priority queue of the "open set", ordered by f(n)
+	open := newOpenSet(ctx.NodeCountMax, ctx.tieBreak)
+	// Nodes already processed
+	closedSet := NewSet[N]()
+	// Keep track of most efficient path to each node
+	cameFrom := make(map[N]N)
 
 	start := ctx.Start
 	gScore[start] = 0
-	fScore[start] = h(start)
 	// First node to process is starting node
-	pushFunc(start)
+	open.Set(start, ctx.heuristic(start))
+	stats.observeOpenSetSize(open.Len())
+	ctx.emit(SearchEventOpen, start, 0, open.Len())
 
-	// Keep track of most efficient path to each node
-	cameFrom := make(map[SearchNode]SearchNode)
-	path := func(destination SearchNode) []SearchNode {
-		result := make([]SearchNode, 0)
-		next := destination
-		for next != start {
-			result = append(result, next)
-			next = cameFrom[next]
-		}
-		// Reverse the path, so it's from start to goal
-		for left, right := 0, len(result)-1; left < right; left, right = left+1, right-1 {
-			result[left], result[right] = result[right], result[left]
-		}
-		return result
-	}
-
-	for openSetQueue.Len() > 0 {
+	for open.Len() > 0 {
 		// Get most promising next node
-		current := popFunc()
+		current, _ := open.Pop()
+		closedSet.Add(current) // Don't visit this node again
+		stats.Expanded++
+		ctx.emit(SearchEventExpand, current, gScore[current], open.Len())
 		// Did we find a goal?
-		for _, d := range ctx.Destinations {
-			if current == d {
-				return path(current), nil
-			}
+		if ctx.isDestination(current) {
+			ctx.emit(SearchEventFound, current, gScore[current], open.Len())
+			return tracePath(cameFrom, start, current), gScore[current], nil
 		}
 
 		// Score potential next nodes
 		for _, neighbour := range ctx.Adjacent(current) {
 			// Calculate new path cost
-			nScore := gScore[current] + ctx.Cost(current, neighbour)
+			nScore := gScore[current] + ctx.cost(current, neighbour)
 
-			if closedSet[neighbour] {
-				// Already have a shortest path to this neighbour
+			if closedSet.Contains(neighbour) {
+				// Already have a shortest path to this neighbour, unless the heuristic is inconsistent
+				if nScore < gScore[neighbour] {
+					stats.Inconsistent++
+				}
 				continue
 			}
-			if !visited[neighbour] {
-				// Position we've never seen before, add to the queue
-				pushFunc(neighbour)
-			} else if nScore > g(neighbour) {
+			eventType := SearchEventReopen
+			if g, ok := gScore[neighbour]; !ok {
+				// Position we've never seen before, will be added to the queue
+				eventType = SearchEventOpen
+			} else if nScore > g {
 				// Already a better path to neighbour
 				continue
-			} else if nScore == g(neighbour) && !ctx.TieBreak(current, cameFrom[neighbour]) {
+			} else if nScore == g && !ctx.tieBreak(current, cameFrom[neighbour]) {
 				// Already an equal path to neighbour which came from a "better" source
 				// (according to the tie break function)
 				continue
+			} else if nScore == g {
+				// An equal path from a "better" source, so the cost doesn't change
+				eventType = SearchEventReparent
+			}
+			// Found a new node, or a better path to a node already in the queue
+			if eventType == SearchEventReopen {
+				stats.Reopened++
 			}
-			// This node is already in the queue, and we've found a better path to it
 			cameFrom[neighbour] = current
 			gScore[neighbour] = nScore
-			fScore[neighbour] = nScore + h(neighbour)
-			// Force the priority queue to update
-			updateFunc(neighbour)
+			open.Set(neighbour, nScore+ctx.heuristic(neighbour))
+			stats.observeOpenSetSize(open.Len())
+			ctx.emit(eventType, neighbour, nScore, open.Len())
 		}
 	}
 
-	return nil, fmt.Errorf("no path found to any destination")
+	return nil, 0, fmt.Errorf("no path found to any destination")
+}
+
+/*
+DijkstraSearch is AStarSearch without a heuristic, i.e. ctx.Heuristic is
+ignored.
+
+https://en.wikipedia.org/wiki/Dijkstra%27s_algorithm
+*/
+func DijkstraSearch[N comparable](ctx *AStarSearchContext[N]) ([]N, int, error) {
+	dijkstra := *ctx
+	dijkstra.Heuristic = nil
+	return AStarSearch(&dijkstra)
+}
+
+/*
+BreadthFirstSearch finds the shortest path from ctx.Start to any of
+ctx.Destinations where every step has the same cost, i.e. ctx.Cost and
+ctx.Heuristic are ignored and the returned cost is the number of steps.
+
+The search proceeds one step-count at a time, so ctx.TieBreak is applied the
+same way as in AStarSearch: it chooses between destinations at the same
+distance, and between predecessors offering equal-length paths to a node.
+*/
+func BreadthFirstSearch[N comparable](ctx *AStarSearchContext[N]) ([]N, int, error) {
+	if len(ctx.Destinations) == 0 {
+		return nil, 0, fmt.Errorf("no destinations")
+	}
+	stats, done := ctx.instrument()
+	defer done()
+
+	start := ctx.Start
+	cameFrom := make(map[N]N)
+	// Distance of each node found so far
+	depth := map[N]int{start: 0}
+	frontier := []N{start}
+	stats.observeOpenSetSize(1)
+	ctx.emit(SearchEventOpen, start, 0, 1)
+
+	for steps := 0; len(frontier) > 0; steps++ {
+		// Did we find a goal? Choose the best one at this distance.
+		found := false
+		var best N
+		for _, n := range frontier {
+			if ctx.isDestination(n) && (!found || ctx.tieBreak(n, best)) {
+				best, found = n, true
+			}
+		}
+		if found {
+			ctx.emit(SearchEventFound, best, steps, 0)
+			return tracePath(cameFrom, start, best), steps, nil
+		}
+
+		next := make([]N, 0, len(frontier))
+		for i, current := range frontier {
+			stats.Expanded++
+			ctx.emit(SearchEventExpand, current, steps, len(frontier)-i-1+len(next))
+			for _, neighbour := range ctx.Adjacent(current) {
+				if d, ok := depth[neighbour]; !ok {
+					// Position we've never seen before
+					depth[neighbour] = steps + 1
+					cameFrom[neighbour] = current
+					next = append(next, neighbour)
+					stats.observeOpenSetSize(len(frontier) - i - 1 + len(next))
+					ctx.emit(SearchEventOpen, neighbour, steps+1, len(frontier)-i-1+len(next))
+				} else if d == steps+1 && ctx.tieBreak(current, cameFrom[neighbour]) {
+					// Equal path to neighbour from a "better" source
+					cameFrom[neighbour] = current
+				}
+			}
+		}
+		frontier = next
+	}
+
+	return nil, 0, fmt.Errorf("no path found to any destination")
+}
+
+/*
+BidirectionalSearch finds the lowest cost path from ctx.Start to any of
+ctx.Destinations by running Dijkstra's algorithm forwards from the start and
+backwards from the destinations at the same time, stopping once the two
+searches can no longer improve on the best path where they have met.
+
+The graph must be undirected: n2 in ctx.Adjacent(n1) implies n1 in
+ctx.Adjacent(n2), and ctx.Cost(n1, n2) == ctx.Cost(n2, n1). ctx.Heuristic is
+ignored, and ctx.TieBreak only orders each search's queue, so which of several
+equal-cost paths is returned is not guaranteed.
+
+https://en.wikipedia.org/wiki/Bidirectional_search
+*/
+func BidirectionalSearch[N comparable](ctx *AStarSearchContext[N]) ([]N, int, error) {
+	if len(ctx.Destinations) == 0 {
+		return nil, 0, fmt.Errorf("no destinations")
+	}
+	stats, done := ctx.instrument()
+	defer done()
+
+	type side struct {
+		gScore   map[N]int
+		cameFrom map[N]N
+		open     *openSet[N]
+		closed   Set[N]
+	}
+	newSide := func() *side {
+		return &side{
+			gScore:   make(map[N]int),
+			cameFrom: make(map[N]N),
+			open:     newOpenSet(ctx.NodeCountMax/2, ctx.tieBreak),
+			closed:   NewSet[N](),
+		}
+	}
+	forward, backward := newSide(), newSide()
+
+	openSetSize := func() int {
+		return forward.open.Len() + backward.open.Len()
+	}
+	forward.gScore[ctx.Start] = 0
+	forward.open.Set(ctx.Start, 0)
+	ctx.emit(SearchEventOpen, ctx.Start, 0, openSetSize())
+	for _, d := range ctx.Destinations {
+		backward.gScore[d] = 0
+		backward.open.Set(d, 0)
+		ctx.emit(SearchEventOpen, d, 0, openSetSize())
+	}
+	stats.observeOpenSetSize(openSetSize())
+
+	// Best path found so far, as the node where the two searches meet
+	bestCost := math.MaxInt32
+	var meet N
+	found := false
+	consider := func(n N) {
+		gF, okF := forward.gScore[n]
+		gB, okB := backward.gScore[n]
+		if okF && okB && gF+gB < bestCost {
+			bestCost = gF + gB
+			meet = n
+			found = true
+		}
+	}
+	consider(ctx.Start)
+
+	for forward.open.Len() > 0 && backward.open.Len() > 0 {
+		_, topF := forward.open.Peek()
+		_, topB := backward.open.Peek()
+		if topF+topB >= bestCost {
+			// Neither search can find a cheaper path than the one we have
+			break
+		}
+		// Expand whichever search has the smaller frontier
+		this := forward
+		if backward.open.Len() < forward.open.Len() {
+			this = backward
+		}
+		current, _ := this.open.Pop()
+		this.closed.Add(current)
+		stats.Expanded++
+		ctx.emit(SearchEventExpand, current, this.gScore[current], openSetSize())
+		for _, neighbour := range ctx.Adjacent(current) {
+			if this.closed.Contains(neighbour) {
+				continue
+			}
+			nScore := this.gScore[current] + ctx.cost(current, neighbour)
+			eventType := SearchEventOpen
+			if g, ok := this.gScore[neighbour]; ok && nScore >= g {
+				continue
+			} else if ok {
+				eventType = SearchEventReopen
+				stats.Reopened++
+			}
+			this.cameFrom[neighbour] = current
+			this.gScore[neighbour] = nScore
+			this.open.Set(neighbour, nScore)
+			stats.observeOpenSetSize(openSetSize())
+			ctx.emit(eventType, neighbour, nScore, openSetSize())
+			consider(neighbour)
+		}
+	}
+
+	if !found {
+		return nil, 0, fmt.Errorf("no path found to any destination")
+	}
+	ctx.emit(SearchEventFound, meet, bestCost, openSetSize())
+
+	// Forward half of the path, from start to the meeting point
+	path := tracePath(forward.cameFrom, ctx.Start, meet)
+	// Backward half of the path, from the meeting point to a destination
+	for next := meet; ; {
+		prev, ok := backward.cameFrom[next]
+		if !ok {
+			break
+		}
+		path = append(path, prev)
+		next = prev
+	}
+	return path, bestCost, nil
 }
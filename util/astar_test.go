@@ -0,0 +1,191 @@
+package util
+
+import (
+	"testing"
+)
+
+func makeMazeSearch(maze []string, start, destination Vec2D) AStarSearchContext[Vec2D] {
+	return AStarSearchContext[Vec2D]{
+		Start:        start,
+		Destinations: []Vec2D{destination},
+		NodeCountMax: len(maze) * len(maze[0]),
+		Adjacent: func(n Vec2D) []Vec2D {
+			result := make([]Vec2D, 0, 4)
+			for _, offset := range []Vec2D{{0, -1}, {-1, 0}, {1, 0}, {0, 1}} {
+				p := n.Add(offset)
+				if p.Y >= 0 && p.Y < len(maze) && p.X >= 0 && p.X < len(maze[p.Y]) && maze[p.Y][p.X] != '#' {
+					result = append(result, p)
+				}
+			}
+			return result
+		},
+		Heuristic: func(n1, n2 Vec2D) int {
+			return n2.Sub(n1).Manhattan()
+		},
+		TieBreak: func(n1, n2 Vec2D) bool {
+			return n1.Y < n2.Y || (n1.Y == n2.Y && n1.X < n2.X)
+		},
+	}
+}
+
+func TestSearch(t *testing.T) {
+	maze := []string{
+		"..........",
+		".########.",
+		".#......#.",
+		".#.####.#.",
+		".#....#...",
+		".######.#.",
+		"........#.",
+	}
+	searches := []struct {
+		name   string
+		search func(ctx *AStarSearchContext[Vec2D]) ([]Vec2D, int, error)
+	}{
+		{"AStarSearch", AStarSearch[Vec2D]},
+		{"DijkstraSearch", DijkstraSearch[Vec2D]},
+		{"BreadthFirstSearch", BreadthFirstSearch[Vec2D]},
+		{"BidirectionalSearch", BidirectionalSearch[Vec2D]},
+	}
+	tables := []struct {
+		start, destination Vec2D
+		cost               int
+	}{
+		{Vec2D{0, 0}, Vec2D{0, 0}, 0},
+		{Vec2D{0, 0}, Vec2D{9, 0}, 9},
+		{Vec2D{0, 0}, Vec2D{2, 4}, 24},
+		{Vec2D{2, 4}, Vec2D{0, 6}, 18},
+		{Vec2D{9, 6}, Vec2D{7, 6}, 6},
+	}
+
+	for _, s := range searches {
+		for _, table := range tables {
+			ctx := makeMazeSearch(maze, table.start, table.destination)
+			path, cost, err := s.search(&ctx)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", s.name, err)
+				continue
+			}
+			if cost != table.cost || len(path) != table.cost {
+				t.Errorf("%s: expected cost %d, got %d (path length %d)", s.name, table.cost, cost, len(path))
+			}
+			if len(path) > 0 && path[len(path)-1] != table.destination {
+				t.Errorf("%s: expected path to end at %v, got %v", s.name, table.destination, path[len(path)-1])
+			}
+		}
+
+		ctx := makeMazeSearch([]string{"..#.."}, Vec2D{0, 0}, Vec2D{4, 0})
+		if _, _, err := s.search(&ctx); err == nil {
+			t.Errorf("%s: expected error for unreachable destination", s.name)
+		}
+	}
+}
+
+func TestBreadthFirstSearchTieBreak(t *testing.T) {
+	maze := []string{
+		"...",
+		"...",
+		"...",
+	}
+	ctx := makeMazeSearch(maze, Vec2D{0, 0}, Vec2D{2, 2})
+	path, _, _ := BreadthFirstSearch(&ctx)
+	expected := []Vec2D{{1, 0}, {2, 0}, {2, 1}, {2, 2}}
+	for i := range expected {
+		if path[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, path)
+			break
+		}
+	}
+}
+
+func TestSearchInstrumentation(t *testing.T) {
+	maze := []string{
+		".....",
+		".###.",
+		".....",
+	}
+	ctx := makeMazeSearch(maze, Vec2D{0, 0}, Vec2D{4, 2})
+	stats := SearchStats{}
+	trace := SearchTrace[Vec2D]{}
+	ctx.Stats = &stats
+	ctx.OnEvent = trace.Record
+	AStarSearch(&ctx)
+
+	if stats.Expanded == 0 || stats.MaxOpenSetSize == 0 {
+		t.Errorf("expected stats to be filled in, got %v", stats)
+	}
+	steps := 0
+	trace.Replay(func(step int, open, closed []Vec2D) {
+		steps = step
+		if len(closed) != step {
+			t.Errorf("expected %d closed nodes, got %d", step, len(closed))
+		}
+	})
+	if steps != stats.Expanded {
+		t.Errorf("expected %d replay steps, got %d", stats.Expanded, steps)
+	}
+	if last := trace.Events[len(trace.Events)-1]; last.Type != SearchEventFound || last.Node != ctx.Destinations[0] {
+		t.Errorf("expected final event to be found %v, got %v %v", ctx.Destinations[0], last.Type, last.Node)
+	}
+
+	// 0 -> 1 or 2 -> 3 -> 4, where the heuristic gets to 3 through 2 first, but
+	// the tie break prefers 1: the path is the same cost, so that isn't reopening
+	graph := map[int][]int{0: {1, 2}, 1: {3}, 2: {3}, 3: {4}}
+	estimate := map[int]int{0: 3, 1: 2, 2: 1, 3: 1, 4: 0}
+	diamond := AStarSearchContext[int]{
+		Start:        0,
+		Destinations: []int{4},
+		Adjacent:     func(n int) []int { return graph[n] },
+		Heuristic:    func(n1, n2 int) int { return estimate[n1] },
+		TieBreak:     func(n1, n2 int) bool { return n1 < n2 },
+	}
+	diamondStats, diamondTrace := SearchStats{}, SearchTrace[int]{}
+	diamond.Stats = &diamondStats
+	diamond.OnEvent = diamondTrace.Record
+	path, _, _ := AStarSearch(&diamond)
+	counts := make(map[SearchEventType]int)
+	for _, e := range diamondTrace.Events {
+		counts[e.Type]++
+	}
+	if len(path) != 3 || path[0] != 1 || diamondStats.Reopened != 0 || counts[SearchEventReopen] != 0 || counts[SearchEventReparent] != 1 {
+		t.Errorf("expected path through 1 by reparenting, got %v, %v and %v", path, diamondStats, counts)
+	}
+}
+
+func TestAllShortestPaths(t *testing.T) {
+	maze := []string{
+		"......",
+		".#.#..",
+		"......",
+	}
+	ctx := makeMazeSearch(maze, Vec2D{0, 0}, Vec2D{2, 2})
+	ctx.Destinations = append(ctx.Destinations, Vec2D{4, 0}, Vec2D{5, 2})
+	paths, err := AllShortestPaths(&ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paths.Cost != 4 {
+		t.Errorf("expected cost 4, got %d", paths.Cost)
+	}
+	expectedDestinations := []Vec2D{{4, 0}, {2, 2}}
+	if len(paths.Destinations) != len(expectedDestinations) || paths.Destinations[0] != expectedDestinations[0] || paths.Destinations[1] != expectedDestinations[1] {
+		t.Errorf("expected destinations %v, got %v", expectedDestinations, paths.Destinations)
+	}
+	expectedSteps := []Vec2D{{1, 0}, {0, 1}}
+	if steps := paths.FirstSteps(Vec2D{2, 2}); len(steps) != 2 || steps[0] != expectedSteps[0] || steps[1] != expectedSteps[1] {
+		t.Errorf("expected first steps %v, got %v", expectedSteps, steps)
+	}
+	if steps := paths.FirstSteps(Vec2D{4, 0}); len(steps) != 1 || steps[0] != expectedSteps[0] {
+		t.Errorf("expected first steps %v, got %v", expectedSteps[:1], steps)
+	}
+
+	// Without destinations, get a distance map of everything reachable
+	ctx.Destinations = nil
+	paths, err = AllShortestPaths(&ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths.Distance) != 16 || paths.Distance[Vec2D{5, 2}] != 7 {
+		t.Errorf("expected 16 nodes with distance 7 to (5,2), got %d nodes with distance %d", len(paths.Distance), paths.Distance[Vec2D{5, 2}])
+	}
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	a, b := NewSet(1, 2, 3, 4), NewSet(3, 4, 5)
+	tables := []struct {
+		name     string
+		set      Set[int]
+		expected []int
+	}{
+		{"union", a.Union(b), []int{1, 2, 3, 4, 5}},
+		{"intersection", a.Intersection(b), []int{3, 4}},
+		{"difference", a.Difference(b), []int{1, 2}},
+		{"original", a, []int{1, 2, 3, 4}},
+	}
+	for _, table := range tables {
+		items := Sorted(table.set)
+		if len(items) != len(table.expected) {
+			t.Errorf("%s: expected %v, got %v", table.name, table.expected, items)
+			continue
+		}
+		for i := range items {
+			if items[i] != table.expected[i] {
+				t.Errorf("%s: expected %v, got %v", table.name, table.expected, items)
+				break
+			}
+		}
+	}
+}
+
+func TestStack(t *testing.T) {
+	s := NewStack[string](0)
+	s.Push("a")
+	s.Push("b")
+	s.Push("c")
+	if top, ok := s.PeekMany(2); !ok || top[0] != "b" || top[1] != "c" {
+		t.Errorf("expected [b c], got %v", top)
+	}
+	if x, ok := s.Pop(); !ok || x != "c" {
+		t.Errorf("expected c, got %v", x)
+	}
+	s.PopMany(2)
+	if x, ok := s.Pop(); ok || x != "" || s.Top() != "" {
+		t.Errorf("expected empty stack, got %v", x)
+	}
+}
+
+func TestDeque(t *testing.T) {
+	var d Deque[int]
+	// Enough to wrap around and grow several times
+	for i := 0; i < 10; i++ {
+		d.PushBack(i)
+		d.PushFront(-i - 1)
+		if x, _ := d.PopBack(); x != i {
+			t.Errorf("expected %d, got %d", i, x)
+		}
+		d.PushBack(i)
+	}
+	if d.Len() != 20 || d.At(0) != -10 || d.At(19) != 9 {
+		t.Errorf("expected -10 to 9, got %d items %d to %d", d.Len(), d.At(0), d.At(d.Len()-1))
+	}
+	for expected := -10; expected < 10; expected++ {
+		if x, ok := d.PopFront(); !ok || x != expected {
+			t.Errorf("expected %d, got %d", expected, x)
+		}
+	}
+	if _, ok := d.PopFront(); ok {
+		t.Errorf("expected empty deque")
+	}
+}
+
+func TestPriorityQueue(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	q := NewPriorityQueue(func(a, b int) bool { return a < b })
+	expected := make([]int, 100)
+	for i := range expected {
+		expected[i] = rng.Intn(50)
+		q.Push(expected[i])
+	}
+	sort.Ints(expected)
+	for _, e := range expected {
+		if x, ok := q.Pop(); !ok || x != e {
+			t.Errorf("expected %d, got %d", e, x)
+		}
+	}
+	if _, ok := q.Pop(); ok {
+		t.Errorf("expected empty queue")
+	}
+}
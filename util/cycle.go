@@ -0,0 +1,121 @@
+package util
+
+/*
+Cycle describes an eventually-periodic sequence of states x0, x1, x2, ...,
+where x(Start) is the first state that repeats, and it repeats every Length
+steps from then on.
+*/
+type Cycle struct {
+	Start  int
+	Length int
+}
+
+// The earliest step with the same state as step n, or n itself if no cycle was found
+func (c Cycle) Equivalent(n int) int {
+	if n < c.Start || c.Length == 0 {
+		return n
+	}
+	return c.Start + (n-c.Start)%c.Length
+}
+
+// Number of complete cycles between Equivalent(n) and n
+func (c Cycle) Repeats(n int) int {
+	if n < c.Start || c.Length == 0 {
+		return 0
+	}
+	return (n - c.Start) / c.Length
+}
+
+/*
+Get the state at step n, by only advancing as far as the equivalent step
+within the first repetition of the cycle.
+*/
+func StateAt[S any](initial S, next func(S) S, c Cycle, n int) S {
+	state := initial
+	for i := c.Equivalent(n); i > 0; i-- {
+		state = next(state)
+	}
+	return state
+}
+
+/*
+Find the cycle in the sequence of states from initial with Floyd's "tortoise
+and hare" algorithm, which only keeps a couple of states at a time. The
+sequence must eventually repeat, otherwise this never returns.
+*/
+func FloydCycle[S any](initial S, next func(S) S, equal func(a, b S) bool) Cycle {
+	// Find a repeat at some multiple of the cycle length
+	tortoise, hare := next(initial), next(next(initial))
+	for !equal(tortoise, hare) {
+		tortoise, hare = next(tortoise), next(next(hare))
+	}
+	// The hare is now a whole number of cycles ahead, so moving both in step
+	// from the start meets at the beginning of the cycle
+	start := 0
+	tortoise = initial
+	for !equal(tortoise, hare) {
+		tortoise, hare = next(tortoise), next(hare)
+		start++
+	}
+	length := 1
+	for hare = next(tortoise); !equal(tortoise, hare); hare = next(hare) {
+		length++
+	}
+	return Cycle{start, length}
+}
+
+/*
+Find the cycle in the sequence of states from initial with Brent's algorithm,
+which finds the cycle length directly and usually needs fewer steps than
+Floyd's. The sequence must eventually repeat, otherwise this never returns.
+*/
+func BrentCycle[S any](initial S, next func(S) S, equal func(a, b S) bool) Cycle {
+	// Search for the cycle length in windows of increasing powers of 2
+	power, length := 1, 1
+	tortoise, hare := initial, next(initial)
+	for !equal(tortoise, hare) {
+		if power == length {
+			tortoise = hare
+			power *= 2
+			length = 0
+		}
+		hare = next(hare)
+		length++
+	}
+	// Keep the hare exactly one cycle ahead, and they meet at the beginning
+	tortoise, hare = initial, initial
+	for i := 0; i < length; i++ {
+		hare = next(hare)
+	}
+	start := 0
+	for !equal(tortoise, hare) {
+		tortoise, hare = next(tortoise), next(hare)
+		start++
+	}
+	return Cycle{start, length}
+}
+
+/*
+Find the cycle in the sequence of states from initial by remembering the key
+of every state seen, where states with the same key are considered equal. The
+history of states is returned too, including the repeated state at the end,
+so any later state can be found with history[cycle.Equivalent(n)].
+
+Gives up after limit steps (unless limit is negative), in which case ok is
+false and history has all limit+1 states.
+*/
+func HistoryCycle[S any, K comparable](initial S, next func(S) S, key func(S) K, limit int) (cycle Cycle, history []S, ok bool) {
+	seen := map[K]int{key(initial): 0}
+	history = []S{initial}
+	state := initial
+	for i := 1; limit < 0 || i <= limit; i++ {
+		state = next(state)
+		history = append(history, state)
+		k := key(state)
+		if prev, found := seen[k]; found {
+			return Cycle{prev, i - prev}, history, true
+		}
+		seen[k] = i
+	}
+	return Cycle{}, history, false
+}
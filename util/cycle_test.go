@@ -0,0 +1,51 @@
+package util
+
+import "testing"
+
+func TestCycle(t *testing.T) {
+	// 0, 1, 2, 3, 4, 5, 6, 7, 3, 4, 5, 6, 7, 3, ...
+	next := func(x int) int {
+		if x == 7 {
+			return 3
+		}
+		return x + 1
+	}
+	equal := func(a, b int) bool { return a == b }
+	key := func(x int) int { return x }
+	expected := Cycle{3, 5}
+
+	if c := FloydCycle(0, next, equal); c != expected {
+		t.Errorf("Floyd: expected %v, got %v", expected, c)
+	}
+	if c := BrentCycle(0, next, equal); c != expected {
+		t.Errorf("Brent: expected %v, got %v", expected, c)
+	}
+	c, history, ok := HistoryCycle(0, next, key, -1)
+	if !ok || c != expected || len(history) != 9 {
+		t.Errorf("History: expected %v, got %v (ok = %v, %d states)", expected, c, ok, len(history))
+	}
+	// Stopping before the cycle starts finds nothing, but the last state is still there
+	if c, history, ok := HistoryCycle(0, next, key, 2); ok || len(history) != 3 || history[c.Equivalent(2)] != 2 || c.Repeats(2) != 0 {
+		t.Errorf("History: expected no cycle within limit, got %v (%d states)", c, len(history))
+	}
+	if _, history, ok := HistoryCycle(0, next, key, 5); ok || len(history) != 6 {
+		t.Errorf("History: expected no cycle within limit, got %d states", len(history))
+	}
+
+	tables := []struct {
+		n, state int
+	}{
+		{2, 2},
+		{7, 7},
+		{8, 3},
+		{1000, 5},
+	}
+	for _, table := range tables {
+		if state := history[c.Equivalent(table.n)]; state != table.state {
+			t.Errorf("step %d: expected %d, got %d", table.n, table.state, state)
+		}
+		if state := StateAt(0, next, c, table.n); state != table.state {
+			t.Errorf("step %d: expected %d, got %d", table.n, table.state, state)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package util
+
+/*
+Deque is a double-ended queue in a ring buffer, so it can be used as a FIFO
+queue without the slice creeping along (and reallocating) as items are
+removed from the front.
+*/
+type Deque[T any] struct {
+	data []T
+	// Position of the front item in data
+	head  int
+	count int
+}
+
+func NewDeque[T any](initSize int) Deque[T] {
+	return Deque[T]{data: make([]T, MaxInt(initSize, 1))}
+}
+
+func (d *Deque[T]) Len() int {
+	return d.count
+}
+
+func (d *Deque[T]) index(i int) int {
+	return (d.head + i) % len(d.data)
+}
+
+func (d *Deque[T]) grow() {
+	if d.count < len(d.data) {
+		return
+	}
+	data := make([]T, MaxInt(2*len(d.data), 1))
+	for i := 0; i < d.count; i++ {
+		data[i] = d.data[d.index(i)]
+	}
+	d.data, d.head = data, 0
+}
+
+func (d *Deque[T]) PushBack(x T) {
+	d.grow()
+	d.data[d.index(d.count)] = x
+	d.count++
+}
+
+func (d *Deque[T]) PushFront(x T) {
+	d.grow()
+	d.head = (d.head + len(d.data) - 1) % len(d.data)
+	d.data[d.head] = x
+	d.count++
+}
+
+func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
+	if d.count == 0 {
+		return zero, false
+	}
+	result := d.data[d.head]
+	d.data[d.head] = zero
+	d.head = d.index(1)
+	d.count--
+	return result, true
+}
+
+func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
+	if d.count == 0 {
+		return zero, false
+	}
+	i := d.index(d.count - 1)
+	result := d.data[i]
+	d.data[i] = zero
+	d.count--
+	return result, true
+}
+
+func (d *Deque[T]) Front() (T, bool) {
+	if d.count == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.data[d.head], true
+}
+
+func (d *Deque[T]) Back() (T, bool) {
+	if d.count == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.data[d.index(d.count-1)], true
+}
+
+// Item i places from the front
+func (d *Deque[T]) At(i int) T {
+	if i < 0 || i >= d.count {
+		panic("deque index out of range")
+	}
+	return d.data[d.index(i)]
+}
@@ -0,0 +1,76 @@
+package util
+
+/*
+Geometry for problems set in Manhattan space, where the "sphere" of points
+within some distance of a center is an octahedron (or in 2D, a diamond).
+*/
+
+// Manhattan distance from p to the closest point in the box (0 if p is inside it)
+func (b Box[V]) Distance(p V) int {
+	return p.Sub(b.Clamp(p)).Manhattan()
+}
+
+func (b Box[V]) Intersects(o Box[V]) bool {
+	return !Box[V]{b.Min.Max(o.Min), b.Max.Min(o.Max)}.Empty()
+}
+
+/*
+Split divides the box in half along every axis, giving up to 2^Dims smaller
+boxes which exactly cover it. An axis which is only 1 point wide is not split,
+so a box containing a single point splits into just itself.
+*/
+func (b Box[V]) Split() []Box[V] {
+	if b.Empty() {
+		return nil
+	}
+	cmin, cmax := b.Min.Components(), b.Max.Components()
+	dims := b.Min.Dims()
+	result := []Box[V]{b}
+	for i := 0; i < dims; i++ {
+		if cmin[i] == cmax[i] {
+			continue
+		}
+		// Lower half includes the midpoint, rounding towards -inf
+		mid := cmin[i] + (cmax[i]-cmin[i])/2
+		halves := make([]Box[V], 0, 2*len(result))
+		for _, r := range result {
+			lower, upper := r.Max.Components(), r.Min.Components()
+			lower[i], upper[i] = mid, mid+1
+			halves = append(halves,
+				Box[V]{r.Min, r.Max.FromComponents(lower)},
+				Box[V]{r.Min.FromComponents(upper), r.Max})
+		}
+		result = halves
+	}
+	return result
+}
+
+/*
+Octahedron is the set of points within Radius of Center by Manhattan distance.
+*/
+type Octahedron struct {
+	Center Vec3D
+	Radius int
+}
+
+func (o Octahedron) Contains(p Vec3D) bool {
+	return o.Center.Sub(p).Manhattan() <= o.Radius
+}
+
+// Does any point of the box lie within the octahedron?
+func (o Octahedron) IntersectsBox(b Box3D) bool {
+	return b.Distance(o.Center) <= o.Radius
+}
+
+// Does the box lie entirely within the octahedron?
+func (o Octahedron) ContainsBox(b Box3D) bool {
+	// Along each axis, the farthest part of the box is one of its faces
+	far := VecAbs(b.Min.Sub(o.Center)).Max(VecAbs(b.Max.Sub(o.Center)))
+	return !b.Empty() && far.Manhattan() <= o.Radius
+}
+
+// Smallest box containing the octahedron
+func (o Octahedron) BoundingBox() Box3D {
+	r := Vec3D{o.Radius, o.Radius, o.Radius}
+	return Box3D{o.Center.Sub(r), o.Center.Add(r)}
+}
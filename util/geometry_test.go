@@ -0,0 +1,57 @@
+package util
+
+import "testing"
+
+func TestBoxSplit(t *testing.T) {
+	tables := []struct {
+		box   Box3D
+		count int
+	}{
+		{Box3D{Vec3D{0, 0, 0}, Vec3D{3, 3, 3}}, 8},
+		{Box3D{Vec3D{-1, 0, 0}, Vec3D{1, 0, 0}}, 2},
+		{Box3D{Vec3D{5, 5, 5}, Vec3D{5, 5, 5}}, 1},
+	}
+	for _, table := range tables {
+		parts := table.box.Split()
+		if len(parts) != table.count {
+			t.Errorf("expected %d parts, got %v", table.count, parts)
+		}
+		volume := 0
+		for i, part := range parts {
+			volume += part.Volume()
+			for _, other := range parts[i+1:] {
+				if part.Intersects(other) {
+					t.Errorf("expected %v not to intersect %v", part, other)
+				}
+			}
+		}
+		if volume != table.box.Volume() {
+			t.Errorf("expected volume %d, got %d", table.box.Volume(), volume)
+		}
+	}
+}
+
+func TestOctahedron(t *testing.T) {
+	o := Octahedron{Vec3D{0, 0, 0}, 3}
+	tables := []struct {
+		box                  Box3D
+		distance             int
+		intersects, contains bool
+	}{
+		{Box3D{Vec3D{-1, -1, -1}, Vec3D{1, 1, 1}}, 0, true, true},
+		{Box3D{Vec3D{-1, -1, -1}, Vec3D{2, 1, 1}}, 0, true, false},
+		{Box3D{Vec3D{1, 1, 1}, Vec3D{5, 5, 5}}, 3, true, false},
+		{Box3D{Vec3D{2, 1, 1}, Vec3D{5, 5, 5}}, 4, false, false},
+	}
+	for _, table := range tables {
+		if d := table.box.Distance(o.Center); d != table.distance {
+			t.Errorf("expected distance %d to %v, got %d", table.distance, table.box, d)
+		}
+		if v := o.IntersectsBox(table.box); v != table.intersects {
+			t.Errorf("expected intersects %v for %v, got %v", table.intersects, table.box, v)
+		}
+		if v := o.ContainsBox(table.box); v != table.contains {
+			t.Errorf("expected contains %v for %v, got %v", table.contains, table.box, v)
+		}
+	}
+}
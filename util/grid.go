@@ -0,0 +1,194 @@
+package util
+
+import "fmt"
+
+// Memory layout of a Grid's elements
+type GridOrder int
+
+const (
+	// Elements in the same row are adjacent
+	RowMajor GridOrder = iota
+	// Elements in the same column are adjacent
+	ColumnMajor
+)
+
+/*
+Grid is a 2D array of T addressed by Vec2D coordinates, where the top-left
+element can be at any coordinates (the "origin"), e.g. to allow negative
+coordinates or to avoid translating puzzle coordinates.
+
+Copying a Grid value gives another view of the same elements, as does
+SubGrid. Use Copy to get a Grid with its own elements.
+*/
+type Grid[T any] struct {
+	origin        Vec2D
+	width, height int
+	order         GridOrder
+	// Position of the origin element in data
+	offset int
+	// Distance in data between adjacent elements in each direction
+	strideX, strideY int
+	data             []T
+}
+
+// Create a row-major grid with its origin at (0, 0)
+func NewGrid[T any](width, height int) Grid[T] {
+	return NewGridWithOrigin[T](Vec2D{0, 0}, width, height, RowMajor)
+}
+
+// Create a grid covering min to max (inclusive)
+func NewGridWithBounds[T any](min, max Vec2D, order GridOrder) Grid[T] {
+	return NewGridWithOrigin[T](min, max.X-min.X+1, max.Y-min.Y+1, order)
+}
+
+func NewGridWithOrigin[T any](origin Vec2D, width, height int, order GridOrder) Grid[T] {
+	g := Grid[T]{
+		origin: origin,
+		width:  width,
+		height: height,
+		order:  order,
+		data:   make([]T, width*height),
+	}
+	switch order {
+	case RowMajor:
+		g.strideX, g.strideY = 1, width
+	case ColumnMajor:
+		g.strideX, g.strideY = height, 1
+	default:
+		panic(fmt.Sprint("invalid grid order: ", order))
+	}
+	return g
+}
+
+func (g *Grid[T]) Width() int {
+	return g.width
+}
+
+func (g *Grid[T]) Height() int {
+	return g.height
+}
+
+// Coordinates of the top-left element
+func (g *Grid[T]) Min() Vec2D {
+	return g.origin
+}
+
+// Coordinates of the bottom-right element
+func (g *Grid[T]) Max() Vec2D {
+	return Vec2D{g.origin.X + g.width - 1, g.origin.Y + g.height - 1}
+}
+
+func (g *Grid[T]) Order() GridOrder {
+	return g.order
+}
+
+func (g *Grid[T]) Valid(p Vec2D) bool {
+	p.SubInPlace(g.origin)
+	return p.X >= 0 && p.X < g.width && p.Y >= 0 && p.Y < g.height
+}
+
+// Position of p in data, which must be within the grid (not just within data, for a SubGrid)
+func (g *Grid[T]) index(p Vec2D) int {
+	if !g.Valid(p) {
+		panic(fmt.Sprintf("%v outside grid %v to %v", p, g.Min(), g.Max()))
+	}
+	return g.offset + (p.X-g.origin.X)*g.strideX + (p.Y-g.origin.Y)*g.strideY
+}
+
+func (g *Grid[T]) At(p Vec2D) *T {
+	return &g.data[g.index(p)]
+}
+
+func (g *Grid[T]) Get(p Vec2D) T {
+	return g.data[g.index(p)]
+}
+
+func (g *Grid[T]) Set(p Vec2D, value T) {
+	g.data[g.index(p)] = value
+}
+
+/*
+Traverse the grid row-by-row, column-by-column (i.e. "reading order"),
+calling visit with each set of coordinates and a pointer to the element at
+those coordinates.
+*/
+func (g *Grid[T]) Traverse(visit func(p Vec2D, data *T)) {
+	max := g.Max()
+	p := g.origin
+	for p.Y = g.origin.Y; p.Y <= max.Y; p.Y++ {
+		for p.X = g.origin.X; p.X <= max.X; p.X++ {
+			visit(p, g.At(p))
+		}
+	}
+}
+
+func (g *Grid[T]) Initialize(value T) {
+	g.Traverse(func(p Vec2D, data *T) {
+		*data = value
+	})
+}
+
+// Offsets of the 4-connected neighbours, in "reading order"
+var neighbours4 = [4]Vec2D{
+	{0, -1},
+	{-1, 0},
+	{1, 0},
+	{0, 1},
+}
+
+// Offsets of the 8-connected neighbours, in "reading order"
+var neighbours8 = [8]Vec2D{
+	{-1, -1},
+	{0, -1},
+	{1, -1},
+	{-1, 0},
+	{1, 0},
+	{-1, 1},
+	{0, 1},
+	{1, 1},
+}
+
+func (g *Grid[T]) neighbours(p Vec2D, offsets []Vec2D) []Vec2D {
+	result := make([]Vec2D, 0, len(offsets))
+	for _, offset := range offsets {
+		if n := p.Add(offset); g.Valid(n) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// Orthogonally adjacent coordinates which are within the grid, in "reading order"
+func (g *Grid[T]) Neighbours4(p Vec2D) []Vec2D {
+	return g.neighbours(p, neighbours4[:])
+}
+
+// Orthogonally and diagonally adjacent coordinates which are within the grid, in "reading order"
+func (g *Grid[T]) Neighbours8(p Vec2D) []Vec2D {
+	return g.neighbours(p, neighbours8[:])
+}
+
+/*
+SubGrid creates a view of the part of the grid from min to max (inclusive),
+which shares elements with the original grid and uses the same coordinates.
+*/
+func (g *Grid[T]) SubGrid(min, max Vec2D) Grid[T] {
+	if !g.Valid(min) || !g.Valid(max) || min.X > max.X || min.Y > max.Y {
+		panic(fmt.Sprintf("invalid sub-grid %v to %v of grid %v to %v", min, max, g.Min(), g.Max()))
+	}
+	sub := *g
+	sub.origin = min
+	sub.width = max.X - min.X + 1
+	sub.height = max.Y - min.Y + 1
+	sub.offset = g.index(min)
+	return sub
+}
+
+// Copy creates a new grid with the same coordinates, order and elements
+func (g *Grid[T]) Copy() Grid[T] {
+	result := NewGridWithOrigin[T](g.origin, g.width, g.height, g.order)
+	g.Traverse(func(p Vec2D, data *T) {
+		*result.At(p) = *data
+	})
+	return result
+}
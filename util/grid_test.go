@@ -0,0 +1,121 @@
+package util
+
+import "testing"
+
+func TestGrid(t *testing.T) {
+	for _, order := range []GridOrder{RowMajor, ColumnMajor} {
+		g := NewGridWithBounds[int](Vec2D{-2, -1}, Vec2D{2, 1}, order)
+		if g.Width() != 5 || g.Height() != 3 {
+			t.Errorf("expected 5x3 grid, got %dx%d", g.Width(), g.Height())
+		}
+		g.Traverse(func(p Vec2D, data *int) {
+			*data = p.X*10 + p.Y
+		})
+		if v := g.Get(Vec2D{-2, -1}); v != -21 {
+			t.Errorf("expected -21, got %d", v)
+		}
+		if g.Valid(Vec2D{3, 0}) || g.Valid(Vec2D{0, -2}) || !g.Valid(Vec2D{-2, 1}) {
+			t.Errorf("incorrect bounds for grid %v to %v", g.Min(), g.Max())
+		}
+
+		sub := g.SubGrid(Vec2D{0, 0}, Vec2D{1, 1})
+		if sub.Width() != 2 || sub.Height() != 2 || sub.Get(Vec2D{1, 1}) != 11 {
+			t.Errorf("incorrect sub-grid %v to %v", sub.Min(), sub.Max())
+		}
+		sub.Set(Vec2D{1, 0}, 99)
+		if v := g.Get(Vec2D{1, 0}); v != 99 {
+			t.Errorf("expected sub-grid to share elements, got %d", v)
+		}
+		c := g.Copy()
+		c.Set(Vec2D{1, 0}, 0)
+		if v := g.Get(Vec2D{1, 0}); v != 99 {
+			t.Errorf("expected copy not to share elements, got %d", v)
+		}
+
+		if n := g.Neighbours8(Vec2D{-2, 0}); len(n) != 5 {
+			t.Errorf("expected 5 neighbours, got %v", n)
+		}
+		expected := []Vec2D{{-2, -1}, {0, -1}, {-1, 0}}
+		if n := g.Neighbours4(Vec2D{-1, -1}); len(n) != 3 || n[0] != expected[0] || n[1] != expected[1] || n[2] != expected[2] {
+			t.Errorf("expected neighbours %v, got %v", expected, n)
+		}
+		if n := sub.Neighbours4(Vec2D{0, 0}); len(n) != 2 {
+			t.Errorf("expected sub-grid neighbours to be clipped, got %v", n)
+		}
+
+		// Out of bounds, even where the element exists in a bigger grid, rather than wrapping around
+		outside := []struct {
+			g *Grid[int]
+			p Vec2D
+		}{
+			{&g, Vec2D{3, 0}},
+			{&g, Vec2D{-3, 1}},
+			{&g, Vec2D{0, 2}},
+			{&sub, Vec2D{2, 0}},
+			{&sub, Vec2D{-1, 1}},
+			{&sub, Vec2D{0, -1}},
+		}
+		for _, o := range outside {
+			for _, access := range []func(){
+				func() { o.g.Get(o.p) },
+				func() { o.g.Set(o.p, 0) },
+				func() { o.g.At(o.p) },
+			} {
+				if !panics(access) {
+					t.Errorf("expected %v to be out of bounds for grid %v to %v", o.p, o.g.Min(), o.g.Max())
+				}
+			}
+		}
+	}
+}
+
+func panics(f func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	f()
+	return false
+}
+
+func TestParseAndRenderGrid(t *testing.T) {
+	input := []string{
+		"#####",
+		"#.E.#",
+		"#G..",
+		"#####",
+	}
+	entities := make(map[Vec2D]byte)
+	g := ParseGrid(input, func(p Vec2D, c byte) byte {
+		switch c {
+		case 'E', 'G':
+			entities[p] = c
+			return '.'
+		default:
+			return c
+		}
+	})
+	if g.Width() != 5 || g.Height() != 4 || len(entities) != 2 || entities[Vec2D{1, 2}] != 'G' {
+		t.Errorf("unexpected %dx%d grid with entities %v", g.Width(), g.Height(), entities)
+	}
+
+	r := GridRenderer[byte]{
+		Glyph:  ByteGlyph,
+		Layers: []GridLayer{PointsLayer([]Vec2D{{3, 1}, {2, 1}}, '+', ""), EntityLayer(entities, AnsiRed)},
+		RowSuffix: func(y int) string {
+			if y == 0 {
+				return " top"
+			}
+			return ""
+		},
+	}
+	expected := "##### top\n#.E+#\n#G.. \n#####\n"
+	if result := r.Render(&g); result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+
+	r.UseColour = true
+	expected = "##### top\n#." + AnsiRed + "E" + AnsiReset + "+#\n#" + AnsiRed + "G" + AnsiReset + ".. \n#####\n"
+	if result := r.Render(&g); result != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, result)
+	}
+}
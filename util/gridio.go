@@ -0,0 +1,146 @@
+package util
+
+import "strings"
+
+// ANSI escape sequences for GridRenderer colours
+const (
+	AnsiReset   = "\x1b[0m"
+	AnsiBold    = "\x1b[1m"
+	AnsiRed     = "\x1b[31m"
+	AnsiGreen   = "\x1b[32m"
+	AnsiYellow  = "\x1b[33m"
+	AnsiBlue    = "\x1b[34m"
+	AnsiMagenta = "\x1b[35m"
+	AnsiCyan    = "\x1b[36m"
+)
+
+/*
+ParseGrid creates a grid from a character map, one string per row, calling
+parse for each character in "reading order" to get the grid element.
+
+Lines shorter than the longest line are padded with spaces. Entity markers
+(e.g. units or carts) can be pulled out of the map by recording them in parse
+and returning the element that belongs underneath them.
+*/
+func ParseGrid[T any](input []string, parse func(p Vec2D, c byte) T) Grid[T] {
+	width := 0
+	for _, line := range input {
+		width = MaxInt(width, len(line))
+	}
+	g := NewGrid[T](width, len(input))
+	g.Traverse(func(p Vec2D, data *T) {
+		c := byte(' ')
+		if line := input[p.Y]; p.X < len(line) {
+			c = line[p.X]
+		}
+		*data = parse(p, c)
+	})
+	return g
+}
+
+/*
+ByteGlyph returns a byte grid element as-is, to use as the ParseGrid parse
+function or GridRenderer glyph function where the elements are just the
+characters of the map.
+*/
+func ByteGlyph(p Vec2D, data byte) byte {
+	return data
+}
+
+/*
+GridLayer is drawn over the top of a grid by GridRenderer, e.g. to show a set
+of interesting points or the positions of entities.
+*/
+type GridLayer struct {
+	// Character to draw at p, if any
+	Glyph func(p Vec2D) (byte, bool)
+	// ANSI escape sequence for the colour of this layer's characters
+	Colour string
+}
+
+// Layer drawing glyph at each of points
+func PointsLayer(points []Vec2D, glyph byte, colour string) GridLayer {
+	set := NewSet(points...)
+	return GridLayer{
+		Glyph: func(p Vec2D) (byte, bool) {
+			return glyph, set.Contains(p)
+		},
+		Colour: colour,
+	}
+}
+
+// Layer drawing glyph wherever mask is true
+func MaskLayer(mask *Grid[bool], glyph byte, colour string) GridLayer {
+	return GridLayer{
+		Glyph: func(p Vec2D) (byte, bool) {
+			return glyph, mask.Valid(p) && mask.Get(p)
+		},
+		Colour: colour,
+	}
+}
+
+// Layer drawing a different glyph for each entity position
+func EntityLayer(entities map[Vec2D]byte, colour string) GridLayer {
+	return GridLayer{
+		Glyph: func(p Vec2D) (byte, bool) {
+			glyph, ok := entities[p]
+			return glyph, ok
+		},
+		Colour: colour,
+	}
+}
+
+/*
+GridRenderer draws a grid as text, one line per row, with any number of
+layers drawn over the top (later layers above earlier ones).
+*/
+type GridRenderer[T any] struct {
+	// Character for each grid element
+	Glyph func(p Vec2D, data T) byte
+	// Optional ANSI escape sequence for the colour of each grid element
+	Colour func(p Vec2D, data T) string
+	Layers []GridLayer
+	// Optional extra text after each row, e.g. details of the entities on it
+	RowSuffix func(y int) string
+	// Use ANSI escape sequences to colour the output
+	UseColour bool
+}
+
+func (r *GridRenderer[T]) Render(g *Grid[T]) string {
+	min, max := g.Min(), g.Max()
+	sb := strings.Builder{}
+	sb.Grow((g.Width() + 1) * g.Height())
+	for y := min.Y; y <= max.Y; y++ {
+		currentColour := ""
+		for x := min.X; x <= max.X; x++ {
+			p := Vec2D{x, y}
+			data := g.Get(p)
+			glyph := r.Glyph(p, data)
+			colour := ""
+			if r.Colour != nil {
+				colour = r.Colour(p, data)
+			}
+			for _, layer := range r.Layers {
+				if layerGlyph, ok := layer.Glyph(p); ok {
+					glyph, colour = layerGlyph, layer.Colour
+				}
+			}
+			if r.UseColour && colour != currentColour {
+				if currentColour != "" {
+					sb.WriteString(AnsiReset)
+				}
+				sb.WriteString(colour)
+				currentColour = colour
+			}
+			sb.WriteByte(glyph)
+		}
+		if r.UseColour && currentColour != "" {
+			sb.WriteString(AnsiReset)
+		}
+		if r.RowSuffix != nil {
+			sb.WriteString(r.RowSuffix(y))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
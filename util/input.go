@@ -0,0 +1,235 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A line of input, and where it came from
+type Line struct {
+	File   string
+	Number int
+	Text   string
+}
+
+// Error for this line, reported as "file:line: ..."
+func (l Line) Errorf(format string, a ...interface{}) error {
+	return &InputError{l.File, l.Number, fmt.Errorf(format, a...)}
+}
+
+// Error caused by bad input at a specific line of a file
+type InputError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+/*
+LineReader streams lines from a reader, without reading the whole input into
+memory first. Check Err after iterating over Lines.
+*/
+type LineReader struct {
+	name    string
+	scanner *bufio.Scanner
+	closer  io.Closer
+	number  int
+}
+
+// Read lines from r, using name to identify it in errors
+func NewLineReader(r io.Reader, name string) *LineReader {
+	return &LineReader{name: name, scanner: bufio.NewScanner(r)}
+}
+
+// Read lines from a file, which is closed by Close
+func OpenLines(name string) (*LineReader, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r := NewLineReader(file, name)
+	r.closer = file
+	return r, nil
+}
+
+// Iterate over the remaining lines
+func (r *LineReader) Lines() iter.Seq[Line] {
+	return func(yield func(Line) bool) {
+		for r.scanner.Scan() {
+			r.number++
+			if !yield(Line{r.name, r.number, r.scanner.Text()}) {
+				return
+			}
+		}
+	}
+}
+
+// The error that stopped iteration, if any
+func (r *LineReader) Err() error {
+	if err := r.scanner.Err(); err != nil {
+		return &InputError{r.name, r.number + 1, err}
+	}
+	return nil
+}
+
+func (r *LineReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+/*
+Read a file as sections separated by blank lines. Runs of several blank lines
+don't create empty sections.
+*/
+func ReadSectionsFromFile(name string) ([][]Line, error) {
+	r, err := OpenLines(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	result := make([][]Line, 0)
+	var section []Line
+	for line := range r.Lines() {
+		if strings.TrimSpace(line.Text) == "" {
+			if len(section) > 0 {
+				result = append(result, section)
+				section = nil
+			}
+			continue
+		}
+		section = append(section, line)
+	}
+	if len(section) > 0 {
+		result = append(result, section)
+	}
+	return result, r.Err()
+}
+
+var intPattern = regexp.MustCompile(`-?\d+`)
+
+// All the (possibly negative) integers in s, ignoring anything else, panicking if one doesn't fit in an int
+func Ints(s string) []int {
+	matches := intPattern.FindAllString(s, -1)
+	result := make([]int, len(matches))
+	for i, m := range matches {
+		// Can only fail on overflow
+		n, err := strconv.Atoi(m)
+		Check(err)
+		result[i] = n
+	}
+	return result
+}
+
+/*
+RecordFormat parses lines into values of struct type T. The format is literal
+text with "{Field}" placeholders, where Field names a field of T (or a nested
+field like "{Position.X}") of integer or string type. Integer placeholders
+also absorb leading spaces, for input that pads numbers into columns.
+*/
+type RecordFormat[T any] struct {
+	format  string
+	pattern *regexp.Regexp
+	fields  [][]int
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([\w.]+)\}`)
+
+// Compile a format, panicking if it doesn't match the fields of T
+func NewRecordFormat[T any](format string) *RecordFormat[T] {
+	f := &RecordFormat[T]{format: format}
+	recordType := reflect.TypeOf((*T)(nil)).Elem()
+	pattern := strings.Builder{}
+	pattern.WriteString(`^\s*`)
+	last := 0
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(format, -1) {
+		pattern.WriteString(regexp.QuoteMeta(format[last:m[0]]))
+		last = m[1]
+		name := format[m[2]:m[3]]
+		field, ok := recordType.FieldByName(strings.Split(name, ".")[0])
+		index := field.Index
+		for _, part := range strings.Split(name, ".")[1:] {
+			if !ok || field.Type.Kind() != reflect.Struct {
+				ok = false
+				break
+			}
+			field, ok = field.Type.FieldByName(part)
+			index = append(index, field.Index...)
+		}
+		if !ok {
+			panic(fmt.Sprintf("record format %q: %v has no field %q", format, recordType, name))
+		}
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			pattern.WriteString(`\s*([-+]?\d+)`)
+		case reflect.String:
+			pattern.WriteString(`(.*?)`)
+		default:
+			panic(fmt.Sprintf("record format %q: field %q has unsupported type %v", format, name, field.Type))
+		}
+		f.fields = append(f.fields, index)
+	}
+	pattern.WriteString(regexp.QuoteMeta(format[last:]))
+	pattern.WriteString(`\s*$`)
+	f.pattern = regexp.MustCompile(pattern.String())
+	return f
+}
+
+func (f *RecordFormat[T]) Parse(line Line) (T, error) {
+	var result T
+	m := f.pattern.FindStringSubmatch(line.Text)
+	if m == nil {
+		return result, line.Errorf("%q doesn't match %q", line.Text, f.format)
+	}
+	v := reflect.ValueOf(&result).Elem()
+	for i, index := range f.fields {
+		field := v.FieldByIndex(index)
+		if field.Kind() == reflect.String {
+			field.SetString(m[i+1])
+			continue
+		}
+		x, err := strconv.ParseInt(m[i+1], 10, field.Type().Bits())
+		if err != nil {
+			return result, line.Errorf("%v", err)
+		}
+		field.SetInt(x)
+	}
+	return result, nil
+}
+
+// Parse every non-blank line of a file with a RecordFormat
+func ReadRecordsFromFile[T any](name string, format string) ([]T, error) {
+	f := NewRecordFormat[T](format)
+	r, err := OpenLines(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	result := make([]T, 0)
+	for line := range r.Lines() {
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		record, err := f.Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+	return result, r.Err()
+}
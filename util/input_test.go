@@ -0,0 +1,69 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInts(t *testing.T) {
+	tables := []struct {
+		input    string
+		expected []int
+	}{
+		{"", []int{}},
+		{"#1 @ 596,731: 11x27", []int{1, 596, 731, 11, 27}},
+		{"position=< -9951, -50547> velocity=< 1,  5>", []int{-9951, -50547, 1, 5}},
+		{"x=532, y=716..727", []int{532, 716, 727}},
+	}
+	for _, table := range tables {
+		result := Ints(table.input)
+		if len(result) != len(table.expected) {
+			t.Errorf("%q: expected %v, got %v", table.input, table.expected, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != table.expected[i] {
+				t.Errorf("%q: expected %v, got %v", table.input, table.expected, result)
+				break
+			}
+		}
+	}
+	if !panics(func() { Ints("x=99999999999999999999") }) {
+		t.Errorf("expected overflow to be reported")
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	r := NewLineReader(strings.NewReader("a\nb\n\nc"), "test.txt")
+	lines := make([]Line, 0)
+	for line := range r.Lines() {
+		lines = append(lines, line)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 || lines[3] != (Line{"test.txt", 4, "c"}) {
+		t.Errorf("unexpected lines %v", lines)
+	}
+}
+
+func TestRecordFormat(t *testing.T) {
+	type star struct {
+		Name               string
+		Position, Velocity Vec2D
+	}
+	f := NewRecordFormat[star]("{Name}: position=<{Position.X}, {Position.Y}> velocity=<{Velocity.X}, {Velocity.Y}>")
+
+	s, err := f.Parse(Line{"test.txt", 1, "Sol: position=< -9951, -50547> velocity=< 1,  5>"})
+	expected := star{"Sol", Vec2D{-9951, -50547}, Vec2D{1, 5}}
+	if err != nil || s != expected {
+		t.Errorf("expected %v, got %v (%v)", expected, s, err)
+	}
+
+	_, err = f.Parse(Line{"test.txt", 7, "Sol: position=<1, 2>"})
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) || inputErr.Line != 7 || !strings.HasPrefix(err.Error(), "test.txt:7: ") {
+		t.Errorf("expected error at test.txt:7, got %v", err)
+	}
+}
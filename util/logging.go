@@ -0,0 +1,211 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// How much detail to log
+type Level int
+
+const (
+	// Log nothing at all
+	LevelNone Level = iota
+	// Progress and results
+	LevelInfo
+	// Intermediate state, e.g. once per iteration of a simulation
+	LevelDebug
+	// Everything, e.g. every step of every unit in a simulation
+	LevelTrace
+)
+
+var levelNames = []string{"none", "info", "debug", "trace"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return LevelNone, fmt.Errorf("unknown log level %q", s)
+}
+
+// Level for loggers whose name starts with Prefix
+type LogFilter struct {
+	Prefix string
+	Level  Level
+}
+
+// Parse filters of the form "day15=trace,day24/battle=debug"
+func ParseLogFilters(s string) ([]LogFilter, error) {
+	result := make([]LogFilter, 0)
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		prefix, levelName, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("log filter %q should be name=level", part)
+		}
+		level, err := ParseLevel(strings.TrimSpace(levelName))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, LogFilter{strings.TrimSpace(prefix), level})
+	}
+	return result, nil
+}
+
+// Shared by a Logger and all loggers derived from it
+type logOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	level   Level
+	filters []LogFilter
+}
+
+// Level for a logger called name, from the longest matching filter
+func (o *logOutput) levelFor(name string) Level {
+	level, matched := o.level, -1
+	for _, f := range o.filters {
+		if len(f.Prefix) > matched && strings.HasPrefix(name, f.Prefix) {
+			level, matched = f.Level, len(f.Prefix)
+		}
+	}
+	return level
+}
+
+/*
+Logger writes messages at or below its level, prefixed with its name and
+followed by its key=value fields. Sub creates a child logger with a longer
+name, e.g. "day15/battle", whose level can be set separately with filters.
+
+A nil *Logger is valid and discards everything, so it's safe to leave a
+Logger field unset.
+*/
+type Logger struct {
+	out    *logOutput
+	name   string
+	level  Level
+	fields string
+}
+
+func NewLogger(w io.Writer, level Level, filters ...LogFilter) *Logger {
+	out := &logOutput{w: w, level: level, filters: filters}
+	return &Logger{out: out, level: out.levelFor("")}
+}
+
+// Child logger, named name under this logger's name
+func (l *Logger) Sub(name string) *Logger {
+	if l == nil {
+		return nil
+	}
+	if l.name != "" {
+		name = l.name + "/" + name
+	}
+	return &Logger{out: l.out, name: name, level: l.out.levelFor(name), fields: l.fields}
+}
+
+// Logger that adds key=value fields to every message, from alternating keys and values
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	if l == nil {
+		return nil
+	}
+	b := strings.Builder{}
+	b.WriteString(l.fields)
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		s := fmt.Sprint(value)
+		if s == "" || strings.ContainsAny(s, " =\"\n") {
+			s = strconv.Quote(s)
+		}
+		fmt.Fprintf(&b, " %v=%s", keyvals[i], s)
+	}
+	return &Logger{out: l.out, name: l.name, level: l.level, fields: b.String()}
+}
+
+func (l *Logger) Name() string {
+	if l == nil {
+		return ""
+	}
+	return l.name
+}
+
+func (l *Logger) Level() Level {
+	if l == nil {
+		return LevelNone
+	}
+	return l.level
+}
+
+// Would a message at level be written? Useful to skip expensive formatting.
+func (l *Logger) Enabled(level Level) bool {
+	return level != LevelNone && level <= l.Level()
+}
+
+func (l *Logger) Log(level Level, msg string) {
+	if !l.Enabled(level) {
+		return
+	}
+	b := strings.Builder{}
+	if l.name != "" {
+		b.WriteString(l.name)
+		b.WriteString(": ")
+	}
+	b.WriteString(strings.TrimSuffix(msg, "\n"))
+	b.WriteString(l.fields)
+	b.WriteByte('\n')
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	io.WriteString(l.out.w, b.String())
+}
+
+func (l *Logger) Infof(format string, a ...interface{}) {
+	if l.Enabled(LevelInfo) {
+		l.Log(LevelInfo, fmt.Sprintf(format, a...))
+	}
+}
+
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	if l.Enabled(LevelDebug) {
+		l.Log(LevelDebug, fmt.Sprintf(format, a...))
+	}
+}
+
+func (l *Logger) Tracef(format string, a ...interface{}) {
+	if l.Enabled(LevelTrace) {
+		l.Log(LevelTrace, fmt.Sprintf(format, a...))
+	}
+}
+
+// Same as Infof, for compatibility with log.Logger
+func (l *Logger) Printf(format string, a ...interface{}) {
+	l.Infof(format, a...)
+}
+
+// Log at info level, formatted like fmt.Print
+func (l *Logger) Print(a ...interface{}) {
+	if l.Enabled(LevelInfo) {
+		l.Log(LevelInfo, fmt.Sprint(a...))
+	}
+}
+
+// Log at info level, formatted like fmt.Println
+func (l *Logger) Println(a ...interface{}) {
+	if l.Enabled(LevelInfo) {
+		l.Log(LevelInfo, fmt.Sprintln(a...))
+	}
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	filters, err := ParseLogFilters("day15=trace, day15part2=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := strings.Builder{}
+	root := NewLogger(&b, LevelInfo, filters...)
+
+	root.Sub("day01").Debugf("hidden")
+	root.Sub("day01").Printf("shown %d", 1)
+	root.Sub("day15part1").Sub("battle").With("round", 3, "unit", "G1(200)").Tracef("moving")
+	root.Sub("day15part2").Printf("hidden")
+	var nilLogger *Logger
+	nilLogger.Sub("x").With("a", 1).Printf("hidden")
+
+	expected := "day01: shown 1\nday15part1/battle: moving round=3 unit=G1(200)\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+
+	if _, err := ParseLogFilters("day15"); err == nil {
+		t.Errorf("expected error for filter without level")
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("expected error for unknown level")
+	}
+}
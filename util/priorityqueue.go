@@ -0,0 +1,73 @@
+package util
+
+/*
+PriorityQueue is a binary heap, where Pop always returns the item that is
+"least" according to the less function, e.g. the cheapest or most promising.
+*/
+type PriorityQueue[T any] struct {
+	less func(a, b T) bool
+	data []T
+}
+
+func NewPriorityQueue[T any](less func(a, b T) bool) PriorityQueue[T] {
+	return PriorityQueue[T]{less: less}
+}
+
+func (q *PriorityQueue[T]) Len() int {
+	return len(q.data)
+}
+
+func (q *PriorityQueue[T]) Push(x T) {
+	q.data = append(q.data, x)
+	q.up(len(q.data) - 1)
+}
+
+func (q *PriorityQueue[T]) Peek() (T, bool) {
+	if len(q.data) == 0 {
+		var zero T
+		return zero, false
+	}
+	return q.data[0], true
+}
+
+func (q *PriorityQueue[T]) Pop() (T, bool) {
+	var zero T
+	if len(q.data) == 0 {
+		return zero, false
+	}
+	result := q.data[0]
+	last := len(q.data) - 1
+	q.data[0] = q.data[last]
+	q.data[last] = zero
+	q.data = q.data[:last]
+	q.down(0)
+	return result, true
+}
+
+func (q *PriorityQueue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.less(q.data[i], q.data[parent]) {
+			break
+		}
+		q.data[i], q.data[parent] = q.data[parent], q.data[i]
+		i = parent
+	}
+}
+
+func (q *PriorityQueue[T]) down(i int) {
+	n := len(q.data)
+	for {
+		least := i
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child < n && q.less(q.data[child], q.data[least]) {
+				least = child
+			}
+		}
+		if least == i {
+			break
+		}
+		q.data[i], q.data[least] = q.data[least], q.data[i]
+		i = least
+	}
+}
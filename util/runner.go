@@ -1,11 +1,12 @@
 package util
 
 import (
-	"log"
+	"math/rand"
 	"sort"
+	"time"
 )
 
-type Implementation func(logger *log.Logger) string
+type Implementation func(logger *Logger) string
 
 type Solution struct {
 	Name string
@@ -26,3 +27,25 @@ func GetSolutions() []Solution {
 	})
 	return result
 }
+
+var seed = time.Now().UnixNano()
+
+// Set the seed used by NewRand, e.g. to reproduce an earlier run
+func SetSeed(s int64) {
+	seed = s
+}
+
+func GetSeed() int64 {
+	return seed
+}
+
+/*
+NewRand creates the random number source for a solution that needs one, so
+that every solution gets the same sequence for the same seed regardless of
+which other solutions ran first. The seed is logged so that a run can be
+reproduced.
+*/
+func NewRand(logger *Logger) *rand.Rand {
+	logger.Printf("random seed: %d", seed)
+	return rand.New(rand.NewSource(seed))
+}
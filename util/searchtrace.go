@@ -0,0 +1,127 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+SearchStats is filled in at the end of a search if AStarSearchContext.Stats is
+set, to help diagnose why a search was slow.
+*/
+type SearchStats struct {
+	// Number of nodes taken from the open set and expanded
+	Expanded int
+	// Largest size reached by the open set
+	MaxOpenSetSize int
+	// Number of times a cheaper path was found to a node already in the open set
+	Reopened int
+	// Number of times a cheaper path was found to a node already expanded, which
+	// means the heuristic is inconsistent and the result may not be optimal
+	Inconsistent int
+	// Time taken by the search
+	Elapsed time.Duration
+}
+
+func (s SearchStats) String() string {
+	return fmt.Sprintf("expanded %d, max open %d, reopened %d, inconsistent %d, in %v",
+		s.Expanded, s.MaxOpenSetSize, s.Reopened, s.Inconsistent, s.Elapsed)
+}
+
+func (s *SearchStats) observeOpenSetSize(n int) {
+	if n > s.MaxOpenSetSize {
+		s.MaxOpenSetSize = n
+	}
+}
+
+type SearchEventType int
+
+const (
+	// Node added to the open set for the first time
+	SearchEventOpen SearchEventType = iota
+	// Cheaper path found to a node already in the open set
+	SearchEventReopen
+	// Node removed from the open set to be expanded
+	SearchEventExpand
+	// Destination reached, search finished
+	SearchEventFound
+	// Equally cheap path found to a node already in the open set, from a node
+	// the tie break prefers, so only the node's parent changes
+	SearchEventReparent
+)
+
+func (t SearchEventType) String() string {
+	switch t {
+	case SearchEventOpen:
+		return "open"
+	case SearchEventReopen:
+		return "reopen"
+	case SearchEventExpand:
+		return "expand"
+	case SearchEventFound:
+		return "found"
+	case SearchEventReparent:
+		return "reparent"
+	default:
+		return fmt.Sprintf("SearchEventType(%d)", int(t))
+	}
+}
+
+/*
+SearchEvent is passed to AStarSearchContext.OnEvent for every change to the
+open set during a search.
+*/
+type SearchEvent[N comparable] struct {
+	Type SearchEventType
+	Node N
+	// Cost of the best known path to Node
+	Cost int
+	// Size of the open set after the event
+	OpenSetSize int
+}
+
+/*
+SearchTrace records the events of a search, e.g. by setting
+AStarSearchContext.OnEvent to trace.Record, so that the progress of the
+search can be replayed afterwards.
+*/
+type SearchTrace[N comparable] struct {
+	Events []SearchEvent[N]
+}
+
+func (t *SearchTrace[N]) Record(e SearchEvent[N]) {
+	t.Events = append(t.Events, e)
+}
+
+/*
+Replay steps through the recorded events, calling visit after each node is
+expanded with the number of nodes expanded so far, the open set ("frontier")
+in the order nodes were first opened, and the closed set in the order nodes
+were expanded.
+*/
+func (t *SearchTrace[N]) Replay(visit func(step int, open, closed []N)) {
+	isOpen := make(map[N]bool)
+	opened := make([]N, 0)
+	closed := make([]N, 0)
+	step := 0
+	for _, e := range t.Events {
+		switch e.Type {
+		case SearchEventOpen:
+			if !isOpen[e.Node] {
+				isOpen[e.Node] = true
+				opened = append(opened, e.Node)
+			}
+		case SearchEventExpand:
+			delete(isOpen, e.Node)
+			closed = append(closed, e.Node)
+			open := make([]N, 0, len(isOpen))
+			for _, n := range opened {
+				if isOpen[n] {
+					open = append(open, n)
+				}
+			}
+			step++
+			visit(step, open, closed)
+		}
+	}
+}
@@ -1,28 +1,99 @@
 package util
 
-type GenericSet map[Generic]struct{}
+import (
+	"cmp"
+	"sort"
+)
 
-func (s *GenericSet) Add(i Generic) {
-	(*s)[i] = struct{}{}
+/*
+Set of comparable values. The zero value is a nil map, so use NewSet (or
+make) before adding to it.
+*/
+type Set[T comparable] map[T]struct{}
+
+func NewSet[T comparable](items ...T) Set[T] {
+	s := make(Set[T], len(items))
+	for _, i := range items {
+		s.Add(i)
+	}
+	return s
+}
+
+func (s Set[T]) Len() int {
+	return len(s)
+}
+
+func (s Set[T]) Add(i T) {
+	s[i] = struct{}{}
 }
 
-func (s *GenericSet) Remove(i Generic) {
-	delete(*s, i)
+func (s Set[T]) Remove(i T) {
+	delete(s, i)
 }
 
-func (s *GenericSet) Contains(i Generic) bool {
-	_, ok := (*s)[i]
+func (s Set[T]) Contains(i T) bool {
+	_, ok := s[i]
 	return ok
 }
 
-func (s *GenericSet) Difference(o *GenericSet) {
-	for k := range *o {
-		s.Remove(k)
+func (s Set[T]) Copy() Set[T] {
+	result := make(Set[T], len(s))
+	for k := range s {
+		result.Add(k)
 	}
+	return result
 }
 
-func (s *GenericSet) Union(o *GenericSet) {
-	for k := range *o {
-		s.Add(k)
+// New set of items in either set
+func (s Set[T]) Union(o Set[T]) Set[T] {
+	result := s.Copy()
+	for k := range o {
+		result.Add(k)
 	}
+	return result
+}
+
+// New set of items in both sets
+func (s Set[T]) Intersection(o Set[T]) Set[T] {
+	result := make(Set[T])
+	for k := range s {
+		if o.Contains(k) {
+			result.Add(k)
+		}
+	}
+	return result
+}
+
+// New set of items in s but not in o
+func (s Set[T]) Difference(o Set[T]) Set[T] {
+	result := make(Set[T])
+	for k := range s {
+		if !o.Contains(k) {
+			result.Add(k)
+		}
+	}
+	return result
+}
+
+// All items, in an unspecified order
+func (s Set[T]) Items() []T {
+	result := make([]T, 0, len(s))
+	for k := range s {
+		result = append(result, k)
+	}
+	return result
+}
+
+// All items, sorted by less
+func (s Set[T]) SortedFunc(less func(a, b T) bool) []T {
+	result := s.Items()
+	sort.Slice(result, func(i, j int) bool {
+		return less(result[i], result[j])
+	})
+	return result
+}
+
+// All items of a set of ordered values, in ascending order
+func Sorted[T cmp.Ordered](s Set[T]) []T {
+	return s.SortedFunc(cmp.Less[T])
 }
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+ShortestPaths holds every cheapest path from Start, as found by
+AllShortestPaths.
+*/
+type ShortestPaths[N comparable] struct {
+	Start N
+	// Cost of the cheapest path from Start to each node expanded by the search
+	Distance map[N]int
+	// Every predecessor of each node in Distance which is on a cheapest path from Start
+	Previous map[N][]N
+	// Cost of the cheapest path to any destination
+	Cost int
+	// Every destination reachable at Cost, ordered by TieBreak
+	Destinations []N
+
+	tieBreak func(n1, n2 N) bool
+}
+
+/*
+FirstSteps finds every node adjacent to Start which is the first step on a
+cheapest path to any of destinations, ordered by TieBreak.
+*/
+func (p *ShortestPaths[N]) FirstSteps(destinations ...N) []N {
+	result := make([]N, 0)
+	visited := NewSet[N]()
+	queue := NewDeque[N](len(destinations))
+	for _, d := range destinations {
+		queue.PushBack(d)
+	}
+	for queue.Len() > 0 {
+		next, _ := queue.PopFront()
+		if visited.Contains(next) {
+			continue
+		}
+		visited.Add(next)
+		for _, prev := range p.Previous[next] {
+			if prev == p.Start {
+				result = append(result, next)
+			} else {
+				queue.PushBack(prev)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return p.tieBreak(result[i], result[j])
+	})
+	return result
+}
+
+/*
+AllShortestPaths runs Dijkstra's algorithm from ctx.Start, but rather than a
+single path it records every cheapest path: the distance to each node, every
+destination reachable at the lowest cost, and all the ways of reaching them.
+ctx.Heuristic is ignored, and every ctx.Cost must be positive.
+
+The search stops once every node as cheap as the nearest destination has been
+expanded, or explores the whole graph if ctx.Destinations is empty, so the
+result doubles as a distance map. If ctx.Destinations is not empty but none
+can be reached, the distance map is returned along with an error.
+*/
+func AllShortestPaths[N comparable](ctx *AStarSearchContext[N]) (*ShortestPaths[N], error) {
+	stats, done := ctx.instrument()
+	defer done()
+
+	result := &ShortestPaths[N]{
+		Start:        ctx.Start,
+		Distance:     make(map[N]int),
+		Previous:     make(map[N][]N),
+		Destinations: make([]N, 0),
+		tieBreak:     ctx.tieBreak,
+	}
+	// Tentative distances of nodes in the open set
+	gScore := map[N]int{ctx.Start: 0}
+	open := newOpenSet(ctx.NodeCountMax, ctx.tieBreak)
+	open.Set(ctx.Start, 0)
+	stats.observeOpenSetSize(open.Len())
+	ctx.emit(SearchEventOpen, ctx.Start, 0, open.Len())
+	found := false
+
+	for open.Len() > 0 {
+		if _, g := open.Peek(); found && g > result.Cost {
+			// Everything else is further away than the nearest destination
+			break
+		}
+		// Equal priorities are popped in tie break order, so destinations end up sorted
+		current, g := open.Pop()
+		result.Distance[current] = g
+		stats.Expanded++
+		ctx.emit(SearchEventExpand, current, g, open.Len())
+		if ctx.isDestination(current) {
+			result.Cost = g
+			result.Destinations = append(result.Destinations, current)
+			found = true
+			ctx.emit(SearchEventFound, current, g, open.Len())
+		}
+
+		for _, neighbour := range ctx.Adjacent(current) {
+			if _, ok := result.Distance[neighbour]; ok {
+				// Already have a shortest path to this neighbour
+				continue
+			}
+			nScore := g + ctx.cost(current, neighbour)
+			eventType := SearchEventOpen
+			if prev, ok := gScore[neighbour]; ok && nScore > prev {
+				// Already a better path to neighbour
+				continue
+			} else if ok && nScore == prev {
+				// Another equally good path to neighbour
+				result.Previous[neighbour] = append(result.Previous[neighbour], current)
+				continue
+			} else if ok {
+				eventType = SearchEventReopen
+				stats.Reopened++
+			}
+			gScore[neighbour] = nScore
+			result.Previous[neighbour] = []N{current}
+			open.Set(neighbour, nScore)
+			stats.observeOpenSetSize(open.Len())
+			ctx.emit(eventType, neighbour, nScore, open.Len())
+		}
+	}
+
+	// Discard predecessors recorded for nodes which were never expanded
+	for n := range result.Previous {
+		if _, ok := result.Distance[n]; !ok {
+			delete(result.Previous, n)
+		}
+	}
+
+	if len(ctx.Destinations) > 0 && !found {
+		return result, fmt.Errorf("no path found to any destination")
+	}
+	return result, nil
+}
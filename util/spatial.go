@@ -0,0 +1,74 @@
+package util
+
+/*
+SpatialIndex buckets points into cubic cells, so that finding the points near
+a position only needs to look at the points in nearby cells rather than every
+point. It works best when the cell size is about the same as the distances
+being searched for.
+*/
+type SpatialIndex[V Vector[V]] struct {
+	cellSize int
+	cells    map[V][]V
+	count    int
+}
+
+func NewSpatialIndex[V Vector[V]](cellSize int) *SpatialIndex[V] {
+	if cellSize < 1 {
+		panic("cell size must be positive")
+	}
+	return &SpatialIndex[V]{cellSize: cellSize, cells: make(map[V][]V)}
+}
+
+// Number of points in the index
+func (s *SpatialIndex[V]) Len() int {
+	return s.count
+}
+
+// Coordinates of the cell containing p
+func (s *SpatialIndex[V]) cell(p V) V {
+	c := p.Components()
+	for i := range c {
+		// Round towards -inf, so cells around 0 aren't twice the size
+		if c[i] < 0 {
+			c[i] = -((-c[i] + s.cellSize - 1) / s.cellSize)
+		} else {
+			c[i] /= s.cellSize
+		}
+	}
+	return p.FromComponents(c)
+}
+
+func (s *SpatialIndex[V]) Add(p V) {
+	cell := s.cell(p)
+	s.cells[cell] = append(s.cells[cell], p)
+	s.count++
+}
+
+/*
+Within finds every point in the index within Manhattan distance of p
+(including p itself, if it's in the index).
+*/
+func (s *SpatialIndex[V]) Within(p V, distance int) []V {
+	result := make([]V, 0)
+	// Every cell within reach along each axis
+	reach := (distance + s.cellSize - 1) / s.cellSize
+	centre := s.cell(p)
+	min := centre.Sub(VecFill[V](reach)).Components()
+	max := centre.Add(VecFill[V](reach)).Components()
+	var visit func(dim int, c [MaxDims]int)
+	visit = func(dim int, c [MaxDims]int) {
+		if dim < 0 {
+			for _, o := range s.cells[p.FromComponents(c)] {
+				if o.Sub(p).Manhattan() <= distance {
+					result = append(result, o)
+				}
+			}
+			return
+		}
+		for c[dim] = min[dim]; c[dim] <= max[dim]; c[dim]++ {
+			visit(dim-1, c)
+		}
+	}
+	visit(p.Dims()-1, [MaxDims]int{})
+	return result
+}
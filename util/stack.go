@@ -1,39 +1,32 @@
 package util
 
-import "github.com/cheekybits/genny/generic"
-
-//go:generate genny -in=$GOFILE -out=gen-$GOFILE gen "Generic=byte,string"
-
-type Generic generic.Type
-
-type GenericStack struct {
-	Data []Generic
-	Nil Generic
+type Stack[T any] struct {
+	Data []T
 }
 
-func NewGenericStack(initSize int) GenericStack {
-	return GenericStack{Data: make([]Generic, 0, initSize)}
+func NewStack[T any](initSize int) Stack[T] {
+	return Stack[T]{Data: make([]T, 0, initSize)}
 }
 
-func (s *GenericStack) Count() int {
+func (s *Stack[T]) Count() int {
 	return len(s.Data)
 }
 
-func (s *GenericStack) Push(x Generic) {
+func (s *Stack[T]) Push(x T) {
 	s.Data = append(s.Data, x)
 }
 
-func (s *GenericStack) Pop() (Generic, bool) {
+func (s *Stack[T]) Pop() (T, bool) {
 	if result, ok := s.Peek(); !ok {
-		return s.Nil, false
+		return result, false
 	} else {
 		s.Data = s.Data[:len(s.Data)-1]
 		return result, true
 	}
 }
 
-func (s *GenericStack) PopMany(n int) ([]Generic, bool) {
-	result := make([]Generic, 0, n)
+func (s *Stack[T]) PopMany(n int) ([]T, bool) {
+	result := make([]T, 0, n)
 	for ; n > 0 && len(s.Data) > 0; n-- {
 		if next, ok := s.Pop(); ok {
 			result = append(result, next)
@@ -44,16 +37,17 @@ func (s *GenericStack) PopMany(n int) ([]Generic, bool) {
 	return result, true
 }
 
-func (s *GenericStack) Peek() (Generic, bool) {
+func (s *Stack[T]) Peek() (T, bool) {
 	last := len(s.Data) - 1
 	if last < 0 {
-		return s.Nil, false
+		var zero T
+		return zero, false
 	} else {
 		return s.Data[last], true
 	}
 }
 
-func (s *GenericStack) PeekMany(n int) ([]Generic, bool) {
+func (s *Stack[T]) PeekMany(n int) ([]T, bool) {
 	if len(s.Data) < n {
 		return nil, false
 	} else {
@@ -61,10 +55,8 @@ func (s *GenericStack) PeekMany(n int) ([]Generic, bool) {
 	}
 }
 
-func (s *GenericStack) Top() Generic {
-	if len(s.Data) > 0 {
-		return s.Data[len(s.Data)-1]
-	} else {
-		return s.Nil
-	}
+// Top of the stack, or the zero value if the stack is empty
+func (s *Stack[T]) Top() T {
+	result, _ := s.Peek()
+	return result
 }
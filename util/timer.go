@@ -1,21 +1,41 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Timer struct {
-	Log            *log.Logger
+	Log            *Logger
 	Prefix         string
 	StartedAt      time.Time
 	LastCheckpoint time.Time
+	// Root of the tree of spans, and the currently open span
+	spans   *spanNode
+	current *spanNode
+	// Identifies this timer's spans in a trace
+	traceId int64
 }
 
-func NewTimer(log *log.Logger, prefix string) Timer {
+var nextTraceId int64
+
+func NewTimer(log *Logger, prefix string) Timer {
 	now := time.Now()
-	return Timer{log, prefix, now, now}
+	root := &spanNode{}
+	return Timer{
+		Log:            log,
+		Prefix:         prefix,
+		StartedAt:      now,
+		LastCheckpoint: now,
+		spans:          root,
+		current:        root,
+		traceId:        atomic.AddInt64(&nextTraceId, 1),
+	}
 }
 
 func (t *Timer) Checkpoint() (sinceStart time.Duration, sinceLast time.Duration) {
@@ -36,4 +56,141 @@ func (t *Timer) LogCheckpoint(checkpointName string) {
 	sinceStart, sinceLast := t.Checkpoint()
 	t.Log.Printf("%v%v (elapsed %v, total %v)\n",
 		t.Prefix, checkpointName, sinceLast, sinceStart)
+	recordTraceEvent(traceEvent{Name: checkpointName, Cat: t.Log.Name(), Ph: "i", Tid: t.traceId, S: "t"}, t.LastCheckpoint)
+}
+
+// Aggregated timing of every span with the same name and parent
+type spanNode struct {
+	name     string
+	count    int
+	total    time.Duration
+	parent   *spanNode
+	children []*spanNode
+}
+
+func (n *spanNode) child(name string) *spanNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &spanNode{name: name, parent: n}
+	n.children = append(n.children, c)
+	return c
+}
+
+/*
+Span is a named phase of a Timer, which can contain nested spans. Spans that
+are started repeatedly in the same place, e.g. inside a loop, are aggregated
+into a single entry in the Timer's Report.
+*/
+type Span struct {
+	timer *Timer
+	node  *spanNode
+	start time.Time
+}
+
+// Start a span inside the current span, which lasts until End is called
+func (t *Timer) Start(name string) *Span {
+	if t.spans == nil {
+		// Timer not created by NewTimer
+		t.spans = &spanNode{}
+		t.current = t.spans
+	}
+	t.current = t.current.child(name)
+	return &Span{t, t.current, time.Now()}
+}
+
+// End the span (and any spans inside it that are still open)
+func (s *Span) End() time.Duration {
+	d := time.Since(s.start)
+	s.node.count++
+	s.node.total += d
+	s.timer.current = s.node.parent
+	recordTraceEvent(traceEvent{Name: s.node.name, Cat: s.timer.Log.Name(), Ph: "X", Dur: d.Seconds() * 1e6, Tid: s.timer.traceId}, s.start)
+	return d
+}
+
+// Run f inside a span
+func (t *Timer) Time(name string, f func()) time.Duration {
+	s := t.Start(name)
+	f()
+	return s.End()
+}
+
+// Tree of all spans so far, with how many times each ran and how long they took
+func (t *Timer) Report() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "%vtotal %v\n", t.Prefix, time.Since(t.StartedAt))
+	if t.spans != nil {
+		writeSpanReport(&b, t.spans, 1)
+	}
+	return b.String()
+}
+
+func writeSpanReport(b *strings.Builder, n *spanNode, depth int) {
+	for _, c := range n.children {
+		fmt.Fprintf(b, "%s%s: %v", strings.Repeat("  ", depth), c.name, c.total)
+		if c.count > 1 {
+			fmt.Fprintf(b, " (%d times, average %v)", c.count, c.total/time.Duration(c.count))
+		}
+		b.WriteByte('\n')
+		writeSpanReport(b, c, depth+1)
+	}
+}
+
+func (t *Timer) LogReport() {
+	t.Log.Printf("timing report:\n%s", t.Report())
+}
+
+// Event in the Chrome trace event format, as understood by chrome://tracing
+type traceEvent struct {
+	Name string  `json:"name"`
+	Cat  string  `json:"cat,omitempty"`
+	Ph   string  `json:"ph"`
+	Ts   float64 `json:"ts"`
+	Dur  float64 `json:"dur,omitempty"`
+	Pid  int     `json:"pid"`
+	Tid  int64   `json:"tid"`
+	S    string  `json:"s,omitempty"`
+}
+
+var tracing struct {
+	mu      sync.Mutex
+	enabled bool
+	start   time.Time
+	events  []traceEvent
+}
+
+// Start recording every span and checkpoint of every Timer, for WriteTrace
+func StartTrace() {
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+	tracing.enabled = true
+	tracing.start = time.Now()
+	tracing.events = nil
+}
+
+// Record e as happening at time at, if tracing has been started
+func recordTraceEvent(e traceEvent, at time.Time) {
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+	if tracing.enabled {
+		// Timestamps are in microseconds
+		e.Ts = at.Sub(tracing.start).Seconds() * 1e6
+		tracing.events = append(tracing.events, e)
+	}
+}
+
+// Write everything recorded since StartTrace as Chrome trace JSON
+func WriteTrace(w io.Writer) error {
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+	events := tracing.events
+	if events == nil {
+		events = []traceEvent{}
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}{events})
 }
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTimerSpans(t *testing.T) {
+	StartTrace()
+	timer := NewTimer(nil, "")
+	for i := 0; i < 3; i++ {
+		outer := timer.Start("outer")
+		timer.Time("inner", func() {})
+		outer.End()
+	}
+	timer.Time("other", func() {})
+
+	lines := strings.Split(strings.TrimSpace(timer.Report()), "\n")
+	expected := []string{"total ", "  outer: ", "    inner: ", "  other: "}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %q", len(expected), lines)
+	}
+	for i := range lines {
+		if !strings.HasPrefix(lines[i], expected[i]) {
+			t.Errorf("expected %q to start with %q", lines[i], expected[i])
+		}
+	}
+	if !strings.Contains(lines[1], "(3 times") || strings.Contains(lines[3], "times") {
+		t.Errorf("incorrect counts in %q", lines)
+	}
+
+	b := strings.Builder{}
+	if err := WriteTrace(&b); err != nil {
+		t.Fatal(err)
+	}
+	trace := struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}{}
+	if err := json.Unmarshal([]byte(b.String()), &trace); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.TraceEvents) != 7 {
+		t.Errorf("expected 7 events, got %d", len(trace.TraceEvents))
+	}
+}
@@ -0,0 +1,112 @@
+package util
+
+/*
+UnionFind is a disjoint-set forest: it tracks which elements are in the same
+set as each other, as sets are merged together. With path compression and
+union by rank, each operation takes effectively constant time.
+*/
+type UnionFind[T comparable] struct {
+	index  map[T]int
+	items  []T
+	parent []int
+	rank   []uint8
+	size   []int
+	sets   int
+}
+
+func NewUnionFind[T comparable]() *UnionFind[T] {
+	return &UnionFind[T]{index: make(map[T]int)}
+}
+
+// Number of elements
+func (u *UnionFind[T]) Len() int {
+	return len(u.items)
+}
+
+// Number of disjoint sets
+func (u *UnionFind[T]) Sets() int {
+	return u.sets
+}
+
+// Add x as a new set on its own, unless it has already been added
+func (u *UnionFind[T]) Add(x T) bool {
+	if _, ok := u.index[x]; ok {
+		return false
+	}
+	i := len(u.items)
+	u.index[x] = i
+	u.items = append(u.items, x)
+	u.parent = append(u.parent, i)
+	u.rank = append(u.rank, 0)
+	u.size = append(u.size, 1)
+	u.sets++
+	return true
+}
+
+func (u *UnionFind[T]) find(i int) int {
+	root := i
+	for u.parent[root] != root {
+		root = u.parent[root]
+	}
+	// Path compression: point everything on the way directly at the root
+	for u.parent[i] != root {
+		u.parent[i], i = root, u.parent[i]
+	}
+	return root
+}
+
+// Get the representative element of the set containing x, adding x if necessary
+func (u *UnionFind[T]) Find(x T) T {
+	u.Add(x)
+	return u.items[u.find(u.index[x])]
+}
+
+/*
+Union merges the sets containing a and b, adding them if necessary, and
+returns false if they were already in the same set.
+*/
+func (u *UnionFind[T]) Union(a, b T) bool {
+	u.Add(a)
+	u.Add(b)
+	ra, rb := u.find(u.index[a]), u.find(u.index[b])
+	if ra == rb {
+		return false
+	}
+	// Union by rank: attach the shallower tree under the deeper one
+	if u.rank[ra] < u.rank[rb] {
+		ra, rb = rb, ra
+	}
+	u.parent[rb] = ra
+	u.size[ra] += u.size[rb]
+	if u.rank[ra] == u.rank[rb] {
+		u.rank[ra]++
+	}
+	u.sets--
+	return true
+}
+
+// Are a and b in the same set? (Elements which haven't been added are in no set.)
+func (u *UnionFind[T]) Connected(a, b T) bool {
+	ia, okA := u.index[a]
+	ib, okB := u.index[b]
+	return okA && okB && u.find(ia) == u.find(ib)
+}
+
+// Number of elements in the set containing x
+func (u *UnionFind[T]) SetSize(x T) int {
+	i, ok := u.index[x]
+	if !ok {
+		return 0
+	}
+	return u.size[u.find(i)]
+}
+
+// Every set, keyed by its representative element, with elements in the order they were added
+func (u *UnionFind[T]) Groups() map[T][]T {
+	result := make(map[T][]T, u.sets)
+	for i, x := range u.items {
+		root := u.items[u.find(i)]
+		result[root] = append(result[root], x)
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestUnionFind(t *testing.T) {
+	u := NewUnionFind[string]()
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		u.Add(s)
+	}
+	tables := []struct {
+		a, b   string
+		merged bool
+		sets   int
+	}{
+		{"a", "b", true, 4},
+		{"c", "d", true, 3},
+		{"b", "a", false, 3},
+		{"d", "a", true, 2},
+		{"c", "b", false, 2},
+		{"f", "e", true, 2},
+	}
+	for _, table := range tables {
+		if merged := u.Union(table.a, table.b); merged != table.merged {
+			t.Errorf("%s+%s: expected merged %v, got %v", table.a, table.b, table.merged, merged)
+		}
+		if sets := u.Sets(); sets != table.sets {
+			t.Errorf("%s+%s: expected %d sets, got %d", table.a, table.b, table.sets, sets)
+		}
+	}
+	if !u.Connected("a", "c") || u.Connected("a", "e") || u.Connected("a", "z") {
+		t.Errorf("incorrect connectivity")
+	}
+	if size := u.SetSize("d"); size != 4 {
+		t.Errorf("expected 4, got %d", size)
+	}
+	if groups := u.Groups(); len(groups) != 2 || len(groups[u.Find("e")]) != 2 {
+		t.Errorf("incorrect groups %v", groups)
+	}
+}
+
+func TestSpatialIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	points := make([]Vec3D, 500)
+	index := NewSpatialIndex[Vec3D](3)
+	for i := range points {
+		points[i] = Vec3D{rng.Intn(41) - 20, rng.Intn(41) - 20, rng.Intn(41) - 20}
+		index.Add(points[i])
+	}
+	for _, distance := range []int{0, 2, 3, 7} {
+		for _, p := range points[:50] {
+			expected := 0
+			for _, o := range points {
+				if o.Sub(p).Manhattan() <= distance {
+					expected++
+				}
+			}
+			if found := index.Within(p, distance); len(found) != expected {
+				t.Errorf("%v within %d: expected %d points, got %d", p, distance, expected, len(found))
+			}
+		}
+	}
+}
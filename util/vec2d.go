@@ -14,53 +14,101 @@ func MinVec2D() Vec2D {
 	return Vec2D{math.MinInt32, math.MinInt32}
 }
 
+func (v Vec2D) Dims() int {
+	return 2
+}
+
+func (v Vec2D) Components() [MaxDims]int {
+	return [MaxDims]int{v.X, v.Y}
+}
+
+func (v Vec2D) FromComponents(c [MaxDims]int) Vec2D {
+	return Vec2D{c[0], c[1]}
+}
+
 func (v Vec2D) Add(o Vec2D) Vec2D {
-	result := v
-	result.AddInPlace(o)
-	return result
+	return Vec2D{v.X + o.X, v.Y + o.Y}
 }
 
 func (v Vec2D) Sub(o Vec2D) Vec2D {
-	result := v
-	result.SubInPlace(o)
-	return result
+	return Vec2D{v.X - o.X, v.Y - o.Y}
 }
 
 func (v Vec2D) Scale(s int) Vec2D {
-	result := v
-	result.X *= s
-	result.Y *= s
-	return result
+	return Vec2D{v.X * s, v.Y * s}
+}
+
+func (v Vec2D) Min(o Vec2D) Vec2D {
+	return Vec2D{min(v.X, o.X), min(v.Y, o.Y)}
+}
+
+func (v Vec2D) Max(o Vec2D) Vec2D {
+	return Vec2D{max(v.X, o.X), max(v.Y, o.Y)}
+}
+
+func (v Vec2D) Mul(o Vec2D) Vec2D {
+	return VecMul(v, o)
+}
+
+func (v Vec2D) Dot(o Vec2D) int {
+	return VecDot(v, o)
+}
+
+func (v Vec2D) Sign() Vec2D {
+	return VecSign(v)
 }
 
 func (v *Vec2D) AddInPlace(o Vec2D) {
-	v.X += o.X
-	v.Y += o.Y
+	*v = v.Add(o)
 }
 
 func (v *Vec2D) SubInPlace(o Vec2D) {
-	v.X -= o.X
-	v.Y -= o.Y
+	*v = v.Sub(o)
 }
 
 func (v *Vec2D) MinInPlace(o Vec2D) {
-	if o.X < v.X { v.X = o.X }
-	if o.Y < v.Y { v.Y = o.Y }
+	*v = v.Min(o)
 }
 
 func (v *Vec2D) MaxInPlace(o Vec2D) {
-	if o.X > v.X { v.X = o.X }
-	if o.Y > v.Y { v.Y = o.Y }
+	*v = v.Max(o)
 }
 
 func (v Vec2D) Area() int {
-	return AbsInt(v.X) * AbsInt(v.Y)
+	return VecProduct(v)
 }
 
 func (v Vec2D) Length() float64 {
-	return math.Sqrt(math.Pow(float64(v.X), 2) + math.Pow(float64(v.Y), 2))
+	return VecLength(v)
 }
 
 func (v Vec2D) Manhattan() int {
 	return AbsInt(v.X) + AbsInt(v.Y)
 }
+
+// Distance when diagonal steps are allowed, i.e. the largest component
+func (v Vec2D) Chebyshev() int {
+	return MaxInt(AbsInt(v.X), AbsInt(v.Y))
+}
+
+// Compare in "reading order", i.e. by the last component first
+func (v Vec2D) ReadingLess(o Vec2D) bool {
+	if v.Y != o.Y {
+		return v.Y < o.Y
+	}
+	return v.X < o.X
+}
+
+func (v Vec2D) Neighbours(diagonal bool) []Vec2D {
+	return VecNeighbours(v, diagonal)
+}
+
+// Rotate 90 degrees clockwise, with Y increasing downwards
+func (v Vec2D) RotateCW() Vec2D {
+	return Vec2D{-v.Y, v.X}
+}
+
+// Rotate 90 degrees anti-clockwise, with Y increasing downwards
+func (v Vec2D) RotateCCW() Vec2D {
+	return Vec2D{v.Y, -v.X}
+}
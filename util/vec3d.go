@@ -14,58 +14,89 @@ func MinVec3D() Vec3D {
 	return Vec3D{math.MinInt32, math.MinInt32, math.MinInt32}
 }
 
+func (v Vec3D) Dims() int {
+	return 3
+}
+
+func (v Vec3D) Components() [MaxDims]int {
+	return [MaxDims]int{v.X, v.Y, v.Z}
+}
+
+func (v Vec3D) FromComponents(c [MaxDims]int) Vec3D {
+	return Vec3D{c[0], c[1], c[2]}
+}
+
 func (v Vec3D) Add(o Vec3D) Vec3D {
-	result := v
-	result.AddInPlace(o)
-	return result
+	return Vec3D{v.X + o.X, v.Y + o.Y, v.Z + o.Z}
 }
 
 func (v Vec3D) Sub(o Vec3D) Vec3D {
-	result := v
-	result.SubInPlace(o)
-	return result
+	return Vec3D{v.X - o.X, v.Y - o.Y, v.Z - o.Z}
 }
 
 func (v Vec3D) Scale(s int) Vec3D {
-	result := v
-	result.X *= s
-	result.Y *= s
-	result.Z *= s
-	return result
+	return Vec3D{v.X * s, v.Y * s, v.Z * s}
+}
+
+func (v Vec3D) Min(o Vec3D) Vec3D {
+	return Vec3D{min(v.X, o.X), min(v.Y, o.Y), min(v.Z, o.Z)}
+}
+
+func (v Vec3D) Max(o Vec3D) Vec3D {
+	return Vec3D{max(v.X, o.X), max(v.Y, o.Y), max(v.Z, o.Z)}
+}
+
+func (v Vec3D) Mul(o Vec3D) Vec3D {
+	return VecMul(v, o)
+}
+
+func (v Vec3D) Dot(o Vec3D) int {
+	return VecDot(v, o)
+}
+
+func (v Vec3D) Sign() Vec3D {
+	return VecSign(v)
 }
 
 func (v *Vec3D) AddInPlace(o Vec3D) {
-	v.X += o.X
-	v.Y += o.Y
-	v.Z += o.Z
+	*v = v.Add(o)
 }
 
 func (v *Vec3D) SubInPlace(o Vec3D) {
-	v.X -= o.X
-	v.Y -= o.Y
-	v.Z -= o.Z
+	*v = v.Sub(o)
 }
 
 func (v *Vec3D) MinInPlace(o Vec3D) {
-	if o.X < v.X { v.X = o.X }
-	if o.Y < v.Y { v.Y = o.Y }
-	if o.Z < v.Z { v.Z = o.Z }
+	*v = v.Min(o)
 }
 
 func (v *Vec3D) MaxInPlace(o Vec3D) {
-	if o.X > v.X { v.X = o.X }
-	if o.Y > v.Y { v.Y = o.Y }
-	if o.Z > v.Z { v.Z = o.Z }
+	*v = v.Max(o)
 }
 
 func (v Vec3D) Volume() int {
-	return AbsInt(v.X) * AbsInt(v.Y) * AbsInt(v.Z)
+	return VecProduct(v)
 }
 
 func (v Vec3D) Length() float64 {
-	return math.Sqrt(math.Pow(float64(v.X), 2) + math.Pow(float64(v.Y), 2) + math.Pow(float64(v.Z), 2))
+	return VecLength(v)
 }
 
 func (v Vec3D) Manhattan() int {
 	return AbsInt(v.X) + AbsInt(v.Y) + AbsInt(v.Z)
 }
+
+// Compare in "reading order", i.e. by the last component first
+func (v Vec3D) ReadingLess(o Vec3D) bool {
+	if v.Z != o.Z {
+		return v.Z < o.Z
+	}
+	if v.Y != o.Y {
+		return v.Y < o.Y
+	}
+	return v.X < o.X
+}
+
+func (v Vec3D) Neighbours(diagonal bool) []Vec3D {
+	return VecNeighbours(v, diagonal)
+}
@@ -14,59 +14,88 @@ func MinVec4D() Vec4D {
 	return Vec4D{math.MinInt32, math.MinInt32, math.MinInt32, math.MinInt32}
 }
 
+func (v Vec4D) Dims() int {
+	return 4
+}
+
+func (v Vec4D) Components() [MaxDims]int {
+	return [MaxDims]int{v.X, v.Y, v.Z, v.T}
+}
+
+func (v Vec4D) FromComponents(c [MaxDims]int) Vec4D {
+	return Vec4D{c[0], c[1], c[2], c[3]}
+}
+
 func (v Vec4D) Add(o Vec4D) Vec4D {
-	result := v
-	result.AddInPlace(o)
-	return result
+	return Vec4D{v.X + o.X, v.Y + o.Y, v.Z + o.Z, v.T + o.T}
 }
 
 func (v Vec4D) Sub(o Vec4D) Vec4D {
-	result := v
-	result.SubInPlace(o)
-	return result
+	return Vec4D{v.X - o.X, v.Y - o.Y, v.Z - o.Z, v.T - o.T}
 }
 
 func (v Vec4D) Scale(s int) Vec4D {
-	result := v
-	result.X *= s
-	result.Y *= s
-	result.Z *= s
-	result.T *= s
-	return result
+	return Vec4D{v.X * s, v.Y * s, v.Z * s, v.T * s}
+}
+
+func (v Vec4D) Min(o Vec4D) Vec4D {
+	return Vec4D{min(v.X, o.X), min(v.Y, o.Y), min(v.Z, o.Z), min(v.T, o.T)}
+}
+
+func (v Vec4D) Max(o Vec4D) Vec4D {
+	return Vec4D{max(v.X, o.X), max(v.Y, o.Y), max(v.Z, o.Z), max(v.T, o.T)}
+}
+
+func (v Vec4D) Mul(o Vec4D) Vec4D {
+	return VecMul(v, o)
+}
+
+func (v Vec4D) Dot(o Vec4D) int {
+	return VecDot(v, o)
+}
+
+func (v Vec4D) Sign() Vec4D {
+	return VecSign(v)
 }
 
 func (v *Vec4D) AddInPlace(o Vec4D) {
-	v.X += o.X
-	v.Y += o.Y
-	v.Z += o.Z
-	v.T += o.T
+	*v = v.Add(o)
 }
 
 func (v *Vec4D) SubInPlace(o Vec4D) {
-	v.X -= o.X
-	v.Y -= o.Y
-	v.Z -= o.Z
-	v.T -= o.T
+	*v = v.Sub(o)
 }
 
 func (v *Vec4D) MinInPlace(o Vec4D) {
-	if o.X < v.X { v.X = o.X }
-	if o.Y < v.Y { v.Y = o.Y }
-	if o.Z < v.Z { v.Z = o.Z }
-	if o.T < v.T { v.T = o.T }
+	*v = v.Min(o)
 }
 
 func (v *Vec4D) MaxInPlace(o Vec4D) {
-	if o.X > v.X { v.X = o.X }
-	if o.Y > v.Y { v.Y = o.Y }
-	if o.Z > v.Z { v.Z = o.Z }
-	if o.T > v.T { v.T = o.T }
+	*v = v.Max(o)
 }
 
 func (v Vec4D) Length() float64 {
-	return math.Sqrt(math.Pow(float64(v.X), 2) + math.Pow(float64(v.Y), 2) + math.Pow(float64(v.Z), 2) + math.Pow(float64(v.T), 2))
+	return VecLength(v)
 }
 
 func (v Vec4D) Manhattan() int {
 	return AbsInt(v.X) + AbsInt(v.Y) + AbsInt(v.Z) + AbsInt(v.T)
 }
+
+// Compare in "reading order", i.e. by the last component first
+func (v Vec4D) ReadingLess(o Vec4D) bool {
+	if v.T != o.T {
+		return v.T < o.T
+	}
+	if v.Z != o.Z {
+		return v.Z < o.Z
+	}
+	if v.Y != o.Y {
+		return v.Y < o.Y
+	}
+	return v.X < o.X
+}
+
+func (v Vec4D) Neighbours(diagonal bool) []Vec4D {
+	return VecNeighbours(v, diagonal)
+}
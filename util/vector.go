@@ -0,0 +1,218 @@
+package util
+
+import "math"
+
+// Maximum number of components of a Vector
+const MaxDims = 4
+
+/*
+Vector is implemented by Vec2D, Vec3D and Vec4D, so that operations on them
+(and types like Box) only need to be written once. Components beyond Dims()
+are always zero, which leaves them unchanged by every operation.
+
+The basic arithmetic is part of the interface rather than written in terms of
+Components, because it's on the hot path of several solutions and generic code
+can't inline it; everything else is built on top.
+*/
+type Vector[V any] interface {
+	comparable
+	// Number of components
+	Dims() int
+	// All components, in X, Y, Z, T order
+	Components() [MaxDims]int
+	// Create a vector of the same type from components (the receiver is ignored)
+	FromComponents(c [MaxDims]int) V
+	Add(o V) V
+	Sub(o V) V
+	// Component-wise minimum
+	Min(o V) V
+	// Component-wise maximum
+	Max(o V) V
+	// Sum of the absolute values of the components
+	Manhattan() int
+}
+
+// Vector with every component set to x
+func VecFill[V Vector[V]](x int) V {
+	var v V
+	var c [MaxDims]int
+	for i := 0; i < v.Dims(); i++ {
+		c[i] = x
+	}
+	return v.FromComponents(c)
+}
+
+// Component-wise product
+func VecMul[V Vector[V]](a, b V) V {
+	ca, cb := a.Components(), b.Components()
+	for i := range ca {
+		ca[i] *= cb[i]
+	}
+	return a.FromComponents(ca)
+}
+
+func VecDot[V Vector[V]](a, b V) int {
+	ca, cb := a.Components(), b.Components()
+	result := 0
+	for i := range ca {
+		result += ca[i] * cb[i]
+	}
+	return result
+}
+
+// Component-wise sign, i.e. -1, 0 or 1
+func VecSign[V Vector[V]](a V) V {
+	ca := a.Components()
+	for i := range ca {
+		switch {
+		case ca[i] < 0:
+			ca[i] = -1
+		case ca[i] > 0:
+			ca[i] = 1
+		}
+	}
+	return a.FromComponents(ca)
+}
+
+// Component-wise absolute value
+func VecAbs[V Vector[V]](a V) V {
+	ca := a.Components()
+	for i := range ca {
+		ca[i] = AbsInt(ca[i])
+	}
+	return a.FromComponents(ca)
+}
+
+// Product of the absolute values of the components
+func VecProduct[V Vector[V]](a V) int {
+	ca := a.Components()
+	result := 1
+	for i := 0; i < a.Dims(); i++ {
+		result *= AbsInt(ca[i])
+	}
+	return result
+}
+
+func VecLength[V Vector[V]](a V) float64 {
+	return math.Sqrt(float64(VecDot(a, a)))
+}
+
+/*
+VecReadingLess compares vectors in "reading order", i.e. by the last
+component first, so for Vec2D top-to-bottom then left-to-right.
+*/
+func VecReadingLess[V Vector[V]](a, b V) bool {
+	ca, cb := a.Components(), b.Components()
+	for i := a.Dims() - 1; i >= 0; i-- {
+		if ca[i] != cb[i] {
+			return ca[i] < cb[i]
+		}
+	}
+	return false
+}
+
+/*
+VecNeighbours finds the vectors adjacent to a, in "reading order": only those
+differing by 1 in a single component, or if diagonal is true, all of those
+differing by at most 1 in each component.
+*/
+func VecNeighbours[V Vector[V]](a V, diagonal bool) []V {
+	dims := a.Dims()
+	result := make([]V, 0)
+	// Every combination of -1, 0, +1 offsets, with the last component varying slowest
+	count := 1
+	for i := 0; i < dims; i++ {
+		count *= 3
+	}
+	ca := a.Components()
+	for n := 0; n < count; n++ {
+		var offset [MaxDims]int
+		changed := 0
+		for i, rest := 0, n; i < dims; i, rest = i+1, rest/3 {
+			offset[i] = rest%3 - 1
+			if offset[i] != 0 {
+				changed++
+			}
+		}
+		if changed == 0 || (!diagonal && changed > 1) {
+			continue
+		}
+		c := ca
+		for i := range c {
+			c[i] += offset[i]
+		}
+		result = append(result, a.FromComponents(c))
+	}
+	return result
+}
+
+/*
+Box is an axis-aligned bounding box, from Min to Max inclusive.
+*/
+type Box[V Vector[V]] struct {
+	Min, Max V
+}
+
+type Box2D = Box[Vec2D]
+type Box3D = Box[Vec3D]
+type Box4D = Box[Vec4D]
+
+/*
+EmptyBox creates a box that contains nothing, ready for Extend to grow it to
+enclose some points.
+*/
+func EmptyBox[V Vector[V]]() Box[V] {
+	return Box[V]{VecFill[V](math.MaxInt32), VecFill[V](math.MinInt32)}
+}
+
+// Create the smallest box containing all of points
+func BoxAround[V Vector[V]](points ...V) Box[V] {
+	b := EmptyBox[V]()
+	for _, p := range points {
+		b.Extend(p)
+	}
+	return b
+}
+
+func (b Box[V]) Empty() bool {
+	cmin, cmax := b.Min.Components(), b.Max.Components()
+	for i := range cmin {
+		if cmin[i] > cmax[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Grow the box, if necessary, to contain p
+func (b *Box[V]) Extend(p V) {
+	b.Min = b.Min.Min(p)
+	b.Max = b.Max.Max(p)
+}
+
+func (b Box[V]) Contains(p V) bool {
+	return b.Min.Max(p) == p && b.Max.Min(p) == p
+}
+
+// Number of points along each axis
+func (b Box[V]) Size() V {
+	return b.Max.Sub(b.Min).Add(VecFill[V](1))
+}
+
+// Number of points in the box
+func (b Box[V]) Volume() int {
+	if b.Empty() {
+		return 0
+	}
+	return VecProduct(b.Size())
+}
+
+// Expand the box by d in each direction
+func (b Box[V]) Grow(d V) Box[V] {
+	return Box[V]{b.Min.Sub(d), b.Max.Add(d)}
+}
+
+// Closest point in the box to p
+func (b Box[V]) Clamp(p V) V {
+	return p.Max(b.Min).Min(b.Max)
+}
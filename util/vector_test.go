@@ -0,0 +1,72 @@
+package util
+
+import "testing"
+
+func TestVector(t *testing.T) {
+	a, b := Vec3D{1, -2, 3}, Vec3D{4, 5, -6}
+	if v := a.Add(b); v != (Vec3D{5, 3, -3}) {
+		t.Errorf("expected {5 3 -3}, got %v", v)
+	}
+	if v := a.Mul(b); v != (Vec3D{4, -10, -18}) {
+		t.Errorf("expected {4 -10 -18}, got %v", v)
+	}
+	if v := a.Dot(b); v != -24 {
+		t.Errorf("expected -24, got %d", v)
+	}
+	if v := b.Sign(); v != (Vec3D{1, 1, -1}) {
+		t.Errorf("expected {1 1 -1}, got %v", v)
+	}
+	if v := a.Sub(b).Manhattan(); v != 19 {
+		t.Errorf("expected 19, got %d", v)
+	}
+
+	tables := []struct {
+		v, cw Vec2D
+	}{
+		{Vec2D{1, 0}, Vec2D{0, 1}},
+		{Vec2D{0, 1}, Vec2D{-1, 0}},
+		{Vec2D{-1, 0}, Vec2D{0, -1}},
+		{Vec2D{0, -1}, Vec2D{1, 0}},
+	}
+	for _, table := range tables {
+		if v := table.v.RotateCW(); v != table.cw {
+			t.Errorf("expected %v, got %v", table.cw, v)
+		}
+		if v := table.cw.RotateCCW(); v != table.v {
+			t.Errorf("expected %v, got %v", table.v, v)
+		}
+	}
+
+	if !(Vec2D{5, 0}).ReadingLess(Vec2D{0, 1}) || (Vec2D{1, 1}).ReadingLess(Vec2D{0, 1}) {
+		t.Errorf("incorrect reading order")
+	}
+	if n := (Vec2D{0, 0}).Neighbours(false); len(n) != 4 || n[0] != (Vec2D{0, -1}) || n[3] != (Vec2D{0, 1}) {
+		t.Errorf("expected 4 neighbours in reading order, got %v", n)
+	}
+	if n := (Vec3D{0, 0, 0}).Neighbours(true); len(n) != 26 {
+		t.Errorf("expected 26 neighbours, got %d", len(n))
+	}
+}
+
+func TestBox(t *testing.T) {
+	box := EmptyBox[Vec2D]()
+	if !box.Empty() || box.Volume() != 0 {
+		t.Errorf("expected empty box, got %v", box)
+	}
+	box = BoxAround(Vec2D{1, 5}, Vec2D{-2, 3}, Vec2D{0, 7})
+	if box.Min != (Vec2D{-2, 3}) || box.Max != (Vec2D{1, 7}) {
+		t.Errorf("expected {-2 3} to {1 7}, got %v to %v", box.Min, box.Max)
+	}
+	if v := box.Volume(); v != 20 {
+		t.Errorf("expected 20, got %d", v)
+	}
+	if !box.Contains(Vec2D{1, 3}) || box.Contains(Vec2D{2, 3}) {
+		t.Errorf("incorrect bounds for box %v", box)
+	}
+	if v := box.Clamp(Vec2D{5, -5}); v != (Vec2D{1, 3}) {
+		t.Errorf("expected {1 3}, got %v", v)
+	}
+	if v := box.Grow(Vec2D{1, 0}).Size(); v != (Vec2D{6, 5}) {
+		t.Errorf("expected {6 5}, got %v", v)
+	}
+}
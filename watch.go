@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alanbriolat/AdventOfCode2018/util"
+)
+
+var watch = flag.Bool("watch", false, "re-run solutions whenever their source or input files change")
+var watchInterval = flag.Duration("watch-interval", time.Second, "how often to check for changes in -watch mode")
+
+// Changes here could affect any solution
+var sharedPaths = []string{"main.go", "util", "elfcode"}
+
+// Answer line logged by main for each solution
+var answerPattern = regexp.MustCompile(`(?ms)^main: (\S+) answer: (.*?) \(elapsed ([^,]+), total [^)]*\)$`)
+
+var dayPattern = regexp.MustCompile(`^day\d+`)
+
+type watchResult struct {
+	Answer  string
+	Elapsed string
+}
+
+/*
+Watch the directories of the selected solutions (or all solutions), and
+whenever a file changes, rebuild and re-run the solutions it could affect in a
+separate process. Never returns: a failed build or run is reported, and then
+it keeps watching.
+*/
+func watchSolutions(log *util.Logger, names []string) {
+	if len(names) == 0 {
+		for _, s := range util.GetSolutions() {
+			names = append(names, s.Name)
+		}
+	}
+	dirs := make([]string, 0)
+	for _, name := range names {
+		if dir := dayPattern.FindString(name); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	results := make(map[string]watchResult)
+	mtimes := scanModTimes(append(dirs, sharedPaths...))
+	rerun := names
+	for {
+		if len(rerun) > 0 {
+			log.Printf("running %s", strings.Join(rerun, " "))
+			runWatchedSolutions(log, rerun, results)
+			log.Printf("watching for changes...")
+		}
+		time.Sleep(*watchInterval)
+		newMtimes := scanModTimes(append(dirs, sharedPaths...))
+		rerun = affectedSolutions(names, changedPaths(mtimes, newMtimes))
+		mtimes = newMtimes
+	}
+}
+
+// Modification times of every file under paths
+func scanModTimes(paths []string) map[string]time.Time {
+	result := make(map[string]time.Time)
+	for _, root := range paths {
+		// Missing paths aren't a problem, they just might appear later
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				result[path] = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return result
+}
+
+// Paths that were added, removed or modified
+func changedPaths(old, new map[string]time.Time) []string {
+	result := make([]string, 0)
+	for path, t := range new {
+		if oldT, ok := old[path]; !ok || !oldT.Equal(t) {
+			result = append(result, path)
+		}
+	}
+	for path := range old {
+		if _, ok := new[path]; !ok {
+			result = append(result, path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Solutions out of names that could be affected by changes to paths
+func affectedSolutions(names []string, paths []string) []string {
+	dirs := util.NewSet[string]()
+	for _, path := range paths {
+		dir := strings.Split(filepath.ToSlash(path), "/")[0]
+		for _, shared := range sharedPaths {
+			if dir == shared {
+				return names
+			}
+		}
+		dirs.Add(dir)
+	}
+	result := make([]string, 0)
+	for _, name := range names {
+		if dirs.Contains(dayPattern.FindString(name)) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Arguments for the child process to reproduce this process's flags
+func passThroughFlags() []string {
+	result := make([]string, 0)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "watch", "watch-interval", "cpuprofile", "trace":
+			// Doesn't make sense to do these on every run
+		default:
+			result = append(result, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+		}
+	})
+	return result
+}
+
+// Run names with "go run", then report how their answers have changed since last time
+func runWatchedSolutions(log *util.Logger, names []string, results map[string]watchResult) {
+	args := append([]string{"run", "."}, passThroughFlags()...)
+	args = append(args, names...)
+	output := bytes.Buffer{}
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = io.MultiWriter(os.Stdout, &output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	if err := cmd.Run(); err != nil {
+		log.Printf("run failed: %v", err)
+	}
+
+	found := make(map[string]watchResult)
+	for _, m := range answerPattern.FindAllStringSubmatch(output.String(), -1) {
+		found[m[1]] = watchResult{m[2], m[3]}
+	}
+	for _, name := range names {
+		result, ok := found[name]
+		prev, hadPrev := results[name]
+		switch {
+		case !ok:
+			log.Printf("%s: no answer", name)
+			continue
+		case !hadPrev:
+			log.Printf("%s: %s (%s)", name, result.Answer, result.Elapsed)
+		case prev.Answer == result.Answer:
+			log.Printf("%s: unchanged %s (%s -> %s)", name, result.Answer, prev.Elapsed, result.Elapsed)
+		default:
+			log.Printf("%s: CHANGED %s -> %s (%s -> %s)", name, prev.Answer, result.Answer, prev.Elapsed, result.Elapsed)
+		}
+		results[name] = result
+	}
+}